@@ -0,0 +1,164 @@
+package path
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchBackend is the source of raw events for a watch. It follows the fsnotify.Watcher API: each added path
+// reports events for itself and, for directories, its direct children.
+type watchBackend interface {
+	Add(path string) error
+	Remove(path string) error
+	WatchList() []string
+	Close() error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+// unwatch stops watching path. The backend may have already dropped the watch when path went away, so the error is
+// ignored.
+func unwatch(watcher watchBackend, path string) {
+	_ = watcher.Remove(path)
+}
+
+// fsnotifyBackend is the watchBackend for the OS notification APIs.
+type fsnotifyBackend struct {
+	*fsnotify.Watcher
+}
+
+func (fb fsnotifyBackend) Events() <-chan fsnotify.Event {
+	return fb.Watcher.Events
+}
+
+func (fb fsnotifyBackend) Errors() <-chan error {
+	return fb.Watcher.Errors
+}
+
+// newWatchBackend picks the backend for a watch. Polling is used for every path when it is forced with WithPolling.
+// With WithPollingFallback it is used for paths on a file system that does not deliver notifications, or for every
+// path when fsnotify is not available.
+func newWatchBackend(config watchConfig) (watchBackend, error) {
+	if config.pollInterval > 0 && !config.pollFallback {
+		return newPollBackend(config.pollInterval, config.clock), nil
+	}
+
+	var watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		if config.pollFallback {
+			return newPollBackend(config.pollInterval, config.clock), nil
+		}
+		return nil, fmt.Errorf("error creating NewWatcher: %w", err)
+	}
+	if config.pollFallback {
+		return newFallbackBackend(fsnotifyBackend{Watcher: watcher}, newPollBackend(config.pollInterval, config.clock)), nil
+	}
+	return fsnotifyBackend{Watcher: watcher}, nil
+}
+
+// fallbackBackend sends each path to notify, or to poll when the path is on a remote file system, and merges the
+// events of both.
+type fallbackBackend struct {
+	notify watchBackend
+	poll   watchBackend
+	events chan fsnotify.Event
+	errors chan error
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+func newFallbackBackend(notify, poll watchBackend) *fallbackBackend {
+	var fb = &fallbackBackend{
+		notify: notify,
+		poll:   poll,
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+		done:   make(chan struct{}),
+	}
+
+	fb.wg.Add(2)
+	go fb.forward(notify)
+	go fb.forward(poll)
+	go func() {
+		fb.wg.Wait()
+		close(fb.events)
+		close(fb.errors)
+	}()
+	return fb
+}
+
+// forward copies the events and errors of source until it is closed.
+func (fb *fallbackBackend) forward(source watchBackend) {
+	defer fb.wg.Done()
+
+	var events, errors = source.Events(), source.Errors()
+	for events != nil || errors != nil {
+		select {
+		case event, open := <-events:
+			if !open {
+				events = nil
+				continue
+			}
+			select {
+			case fb.events <- event:
+			case <-fb.done:
+			}
+
+		case err, open := <-errors:
+			if !open {
+				errors = nil
+				continue
+			}
+			select {
+			case fb.errors <- err:
+			case <-fb.done:
+			}
+		}
+	}
+}
+
+func (fb *fallbackBackend) backendFor(path string) watchBackend {
+	if isRemoteFileSystem(path) {
+		return fb.poll
+	}
+	return fb.notify
+}
+
+func (fb *fallbackBackend) Add(path string) error {
+	return fb.backendFor(path).Add(path)
+}
+
+func (fb *fallbackBackend) Remove(path string) error {
+	if err := fb.notify.Remove(path); err == nil {
+		return nil
+	}
+	return fb.poll.Remove(path)
+}
+
+func (fb *fallbackBackend) WatchList() []string {
+	return append(fb.notify.WatchList(), fb.poll.WatchList()...)
+}
+
+// Close closes both backends, the merged channels are closed once their channels are.
+func (fb *fallbackBackend) Close() error {
+	var err error
+	fb.once.Do(func() {
+		close(fb.done)
+		err = fb.notify.Close()
+		if pollErr := fb.poll.Close(); err == nil {
+			err = pollErr
+		}
+	})
+	return err
+}
+
+func (fb *fallbackBackend) Events() <-chan fsnotify.Event {
+	return fb.events
+}
+
+func (fb *fallbackBackend) Errors() <-chan error {
+	return fb.errors
+}
@@ -0,0 +1,176 @@
+package path
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Backpressure is what a Subscription does with an event when its buffer is full.
+type Backpressure int
+
+const (
+	// Block waits for the subscriber to make room, every other subscriber waits with it.
+	Block Backpressure = iota
+	// DropOldest drops the oldest buffered event to make room.
+	DropOldest
+	// DropNewest drops the event.
+	DropNewest
+)
+
+// Broker fans the events of one Watcher out to any number of subscribers, each with their own filters, buffer and
+// Backpressure. The Broker reads the Events of the Watcher, its Errors still have to be read by the caller.
+// Every Subscription is closed once the Watcher is.
+type Broker struct {
+	mu     sync.Mutex // guards subs and closed
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// Subscription receives the events of a Broker that pass its filters.
+type Subscription struct {
+	broker  *Broker
+	events  chan WatchEvent
+	filters []WatchFilter
+	policy  Backpressure
+	done    chan struct{} // closed by Unsubscribe, gives up a blocked send
+	mu      sync.Mutex    // held while sending so events is not closed underneath it
+	closed  bool
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// NewBroker starts fanning out the events of watcher.
+func NewBroker(watcher *Watcher) *Broker {
+	var b = &Broker{subs: make(map[*Subscription]struct{})}
+	go b.run(watcher.Events())
+	return b
+}
+
+// run publishes every event to the subscribers and closes them once events is closed.
+func (b *Broker) run(events <-chan WatchEvent) {
+	for event := range events {
+		b.mu.Lock()
+		var subs = make([]*Subscription, 0, len(b.subs))
+		for sub := range b.subs {
+			subs = append(subs, sub)
+		}
+		b.mu.Unlock()
+
+		for _, sub := range subs {
+			sub.publish(event)
+		}
+	}
+
+	b.mu.Lock()
+	b.closed = true
+	var subs = b.subs
+	b.subs = nil
+	b.mu.Unlock()
+
+	for sub := range subs {
+		sub.close()
+	}
+}
+
+// Subscribe returns a Subscription that receives the events that pass every filter. It buffers up to buffer events,
+// what happens when the buffer is full is up to policy. Subscribing to a Broker whose Watcher is closed returns a
+// closed Subscription.
+func (b *Broker) Subscribe(buffer int, policy Backpressure, filters ...WatchFilter) *Subscription {
+	var sub = &Subscription{
+		broker:  b,
+		events:  make(chan WatchEvent, max(buffer, 0)),
+		filters: filters,
+		policy:  policy,
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		sub.close()
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Events returns the channel events are published on, it is closed by Unsubscribe or once the Watcher is closed.
+func (s *Subscription) Events() <-chan WatchEvent {
+	return s.events
+}
+
+// Dropped returns how many events were dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe stops the Subscription and closes its channel, events that are buffered are dropped.
+func (s *Subscription) Unsubscribe() {
+	s.broker.mu.Lock()
+	delete(s.broker.subs, s)
+	s.broker.mu.Unlock()
+
+	s.close()
+}
+
+// close gives up a blocked send and closes events.
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.events)
+	})
+}
+
+// publish sends event if it passes the filters, following the Backpressure when the buffer is full.
+func (s *Subscription) publish(event WatchEvent) {
+	for _, fn := range s.filters {
+		if accepted, err := fn.filter(event); err != nil || !accepted {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case Block:
+		select {
+		case s.events <- event:
+		case <-s.done:
+		}
+
+	case DropOldest:
+		for {
+			select {
+			case s.events <- event:
+				return
+			default:
+			}
+			if cap(s.events) == 0 { // there is no oldest
+				s.dropped.Add(1)
+				return
+			}
+			select {
+			case <-s.events:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+
+	case DropNewest:
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
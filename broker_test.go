@@ -0,0 +1,103 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionBackpressure(t *testing.T) {
+	t.Parallel()
+
+	var event = func(name string) WatchEvent {
+		return WatchEvent{Entry: Entry{AbsolutePath: name}, Op: fsnotify.Create}
+	}
+	var drain = func(sub *Subscription) []string {
+		var names []string
+		for len(sub.Events()) > 0 {
+			names = append(names, (<-sub.Events()).AbsolutePath)
+		}
+		return names
+	}
+	var broker = &Broker{subs: make(map[*Subscription]struct{})}
+
+	var oldest = broker.Subscribe(2, DropOldest)
+	var newest = broker.Subscribe(2, DropNewest)
+	var filtered = broker.Subscribe(2, DropNewest, NewRegexWatchFilter(regexp.MustCompile(`b$`)))
+	for _, name := range []string{"a", "b", "c"} {
+		for _, sub := range []*Subscription{oldest, newest, filtered} {
+			sub.publish(event(name))
+		}
+	}
+	assert.Equal(t, []string{"b", "c"}, drain(oldest))
+	assert.Equal(t, uint64(1), oldest.Dropped())
+	assert.Equal(t, []string{"a", "b"}, drain(newest))
+	assert.Equal(t, uint64(1), newest.Dropped())
+	assert.Equal(t, []string{"b"}, drain(filtered))
+	assert.Equal(t, uint64(0), filtered.Dropped())
+
+	// a blocked send gives up when the subscriber leaves
+	var block = broker.Subscribe(0, Block)
+	var sent = make(chan struct{})
+	go func() {
+		block.publish(event("a"))
+		close(sent)
+	}()
+	assert.Equal(t, "a", (<-block.Events()).AbsolutePath)
+	<-sent
+
+	var gaveUp = make(chan struct{})
+	go func() {
+		block.publish(event("b"))
+		close(gaveUp)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	block.Unsubscribe()
+	<-gaveUp
+	var _, open = <-block.Events()
+	assert.False(t, open)
+	assert.Len(t, broker.subs, 3)
+}
+
+func TestBroker(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "logs"), os.ModePerm))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "conf"), os.ModePerm))
+
+	var watcher, err = NewWatcher(t.Context(), 1)
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Add(dir))
+	go func() {
+		for err := range watcher.Errors() {
+			assert.NoError(t, err)
+		}
+	}()
+
+	var broker = NewBroker(watcher)
+	var logs = broker.Subscribe(10, Block, NewRegexWatchFilter(regexp.MustCompile(regexp.QuoteMeta(filepath.Join(dir, "logs")+string(filepath.Separator)))))
+	var yaml = broker.Subscribe(10, DropNewest, NewRegexWatchFilter(regexp.MustCompile(`\.yaml$`)), NewOpWatchFilter(fsnotify.Create))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "app.log"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "conf", "app.yaml"), []byte{}, os.ModePerm))
+
+	var event = <-logs.Events()
+	assert.Equal(t, filepath.Join(dir, "logs", "app.log"), event.AbsolutePath)
+	event = <-yaml.Events()
+	assert.Equal(t, filepath.Join(dir, "conf", "app.yaml"), event.AbsolutePath)
+
+	// every subscription is closed along with the watcher
+	assert.NoError(t, watcher.Close())
+	for range logs.Events() { //nolint:revive // drain anything published before the close
+	}
+	for range yaml.Events() { //nolint:revive // drain anything published before the close
+	}
+	var _, open = <-broker.Subscribe(1, Block).Events()
+	assert.False(t, open)
+}
@@ -0,0 +1,79 @@
+package path
+
+import (
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// metadataCache remembers the last known fs.FileInfo of every path under watch, so events for paths that no longer
+// exist can still be described.
+type metadataCache struct {
+	mu      sync.Mutex
+	entries map[string]fs.FileInfo
+}
+
+func newMetadataCache() *metadataCache {
+	return &metadataCache{entries: make(map[string]fs.FileInfo)}
+}
+
+func (mc *metadataCache) set(path string, info fs.FileInfo) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries[path] = info
+}
+
+func (mc *metadataCache) get(path string) (fs.FileInfo, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	var info, has = mc.entries[path]
+	return info, has
+}
+
+// remove forgets path and, if it is a directory, everything below it.
+func (mc *metadataCache) remove(path string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	delete(mc.entries, path)
+
+	var prefix = path + string(filepath.Separator)
+	for p := range mc.entries {
+		if strings.HasPrefix(p, prefix) {
+			delete(mc.entries, p)
+		}
+	}
+}
+
+// move re-keys oldPath and everything below it to newPath.
+func (mc *metadataCache) move(oldPath, newPath string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if info, has := mc.entries[oldPath]; has {
+		delete(mc.entries, oldPath)
+		mc.entries[newPath] = info
+	}
+
+	var prefix = oldPath + string(filepath.Separator)
+	var moved = make(map[string]fs.FileInfo)
+	for p, info := range mc.entries {
+		if strings.HasPrefix(p, prefix) {
+			delete(mc.entries, p)
+			moved[filepath.Join(newPath, strings.TrimPrefix(p, prefix))] = info
+		}
+	}
+	maps.Copy(mc.entries, moved)
+}
+
+// snapshot returns a copy of every entry.
+func (mc *metadataCache) snapshot() map[string]fs.FileInfo {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	return maps.Clone(mc.entries)
+}
@@ -0,0 +1,46 @@
+package path
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataCache(t *testing.T) {
+	t.Parallel()
+
+	var entry, err = NewEntry("./testdata/", 3)
+	assert.NoError(t, err)
+
+	files, err := entry.Flatten(true)
+	assert.NoError(t, err)
+
+	var cache = newMetadataCache()
+	for _, file := range files {
+		cache.set(file.AbsolutePath, file.FileInfo)
+	}
+	assert.Len(t, cache.entries, 9)
+
+	var one = filepath.Join(entry.AbsolutePath, "one")
+	info, has := cache.get(filepath.Join(one, "file.mp3"))
+	assert.True(t, has)
+	assert.Equal(t, "file.mp3", info.Name())
+
+	// one and its four files are re-keyed
+	var moved = filepath.Join(entry.AbsolutePath, "moved")
+	cache.move(one, moved)
+	assert.Len(t, cache.entries, 9)
+	_, has = cache.get(filepath.Join(one, "file.mp3"))
+	assert.False(t, has)
+	info, has = cache.get(filepath.Join(moved, "file.mp3"))
+	assert.True(t, has)
+	assert.Equal(t, "file.mp3", info.Name())
+
+	// removing a dir removes everything under it, but not siblings that share a prefix
+	cache.set(moved+"2", info)
+	cache.remove(moved)
+	assert.Len(t, cache.entries, 5)
+	_, has = cache.get(moved + "2")
+	assert.True(t, has)
+}
@@ -6,9 +6,15 @@ func (e *Entry) Get() string {
 }
 
 // Set fulfils the flag.Value interface https://pkg.go.dev/flag#Value.
+// Validators attached with WithValidators are checked here so flag.Parse reports them.
 func (e *Entry) Set(s string) error {
 
-	var entry, err = NewEntry(s, 1)
+	var filters = make([]EntriesFilter, len(e.validators))
+	for i, v := range e.validators {
+		filters[i] = v
+	}
+
+	var entry, err = NewEntry(s, 1, filters...)
 	e.AbsolutePath = entry.AbsolutePath
 	e.Children = entry.Children
 	e.FileInfo = entry.FileInfo
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kmulvey/path"
+)
+
+// clearScreen clears the terminal and moves the cursor to the top left.
+const clearScreen = "\033[2J\033[H"
+
+// execFlags are the flags of watch that run a command when something changes.
+type execFlags struct {
+	command  string
+	debounce time.Duration
+	restart  bool
+	clear    bool
+}
+
+// commandRunner runs the -exec command for the paths that changed, one run at a time.
+type commandRunner struct {
+	execFlags
+	stdout io.Writer
+	stderr io.Writer
+	cmd    *exec.Cmd
+	exited chan error // the result of cmd once it exits, nil when nothing is running
+}
+
+// execOnChange runs the command once events has been quiet for the debounce time, with {} replaced by the paths
+// that changed. Changes while the command runs are run after it exits, or restart it with -restart. It returns once
+// events is closed and the last command exited.
+func execOnChange(flags execFlags, events <-chan path.WatchEvent, stdout, stderr io.Writer) {
+	var runner = &commandRunner{execFlags: flags, stdout: stdout, stderr: stderr}
+	defer runner.stop()
+
+	var changed []string
+	var quiet <-chan time.Time
+	for {
+		select {
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			if !slices.Contains(changed, event.AbsolutePath) {
+				changed = append(changed, event.AbsolutePath)
+			}
+			quiet = time.After(flags.debounce)
+
+		case <-quiet:
+			quiet = nil
+			if runner.exited != nil {
+				if !flags.restart {
+					continue // run when it exits
+				}
+				runner.stop()
+			}
+			runner.start(changed)
+			changed = nil
+
+		case err := <-runner.exited:
+			runner.exited = nil
+			if err != nil {
+				fmt.Fprintf(stderr, "path watch: %s: %s\n", flags.command, err)
+			}
+			if len(changed) > 0 && quiet == nil {
+				runner.start(changed)
+				changed = nil
+			}
+		}
+	}
+}
+
+// start runs the command for paths.
+func (cr *commandRunner) start(paths []string) {
+	if cr.clear {
+		fmt.Fprint(cr.stdout, clearScreen)
+	}
+
+	var quoted = make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = shellQuote(p)
+	}
+
+	cr.cmd = shellCommand(strings.ReplaceAll(cr.command, "{}", strings.Join(quoted, " ")))
+	cr.cmd.Stdout = cr.stdout
+	cr.cmd.Stderr = cr.stderr
+	setProcessGroup(cr.cmd)
+
+	var exited = make(chan error, 1)
+	if err := cr.cmd.Start(); err != nil {
+		exited <- err
+	} else {
+		go func(cmd *exec.Cmd) { exited <- cmd.Wait() }(cr.cmd)
+	}
+	cr.exited = exited
+}
+
+// stop kills the running command and everything it started, and waits for it to exit.
+func (cr *commandRunner) stop() {
+	if cr.exited == nil {
+		return
+	}
+	if cr.cmd.Process != nil {
+		killProcessGroup(cr.cmd)
+	}
+	<-cr.exited
+	cr.exited = nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kmulvey/path"
+)
+
+// filterFlags exposes every filter in the path package as a flag, shared by all commands.
+type filterFlags struct {
+	regex    string
+	from     string
+	to       string
+	skip     stringList
+	minPerm  string
+	maxPerm  string
+	minSize  int64
+	maxSize  int64
+	fileType string
+}
+
+// stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+func (sl *stringList) Set(s string) error {
+	*sl = append(*sl, s)
+	return nil
+}
+
+func (ff *filterFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&ff.regex, "regex", "", "only include paths matching this regular expression")
+	fs.StringVar(&ff.from, "from", "", "only include files modified at or after this time (RFC3339 or 2006-01-02)")
+	fs.StringVar(&ff.to, "to", "", "only include files modified at or before this time (RFC3339 or 2006-01-02)")
+	fs.Var(&ff.skip, "skip", "absolute path to exclude, may be repeated")
+	fs.StringVar(&ff.minPerm, "min-perm", "", "only include files with a mode of at least this (octal, e.g. 0600)")
+	fs.StringVar(&ff.maxPerm, "max-perm", "", "only include files with a mode of at most this (octal, e.g. 0755)")
+	fs.Int64Var(&ff.minSize, "min-size", -1, "only include files of at least this many bytes")
+	fs.Int64Var(&ff.maxSize, "max-size", -1, "only include files of at most this many bytes")
+	fs.StringVar(&ff.fileType, "type", "", "only include files (f) or directories (d)")
+}
+
+// filterValues are the parsed flag values shared by entriesFilters and watchFilters.
+type filterValues struct {
+	regex            *regexp.Regexp
+	hasDate          bool
+	from, to         time.Time
+	skipMap          map[string]struct{}
+	hasPerm          bool
+	minPerm, maxPerm uint32
+	hasSize          bool
+	minSize, maxSize int64
+}
+
+func (ff *filterFlags) parse() (filterValues, error) {
+	var fv filterValues
+	var err error
+
+	if ff.regex != "" {
+		if fv.regex, err = regexp.Compile(ff.regex); err != nil {
+			return fv, fmt.Errorf("invalid -regex: %w", err)
+		}
+	}
+
+	if ff.from != "" || ff.to != "" {
+		fv.hasDate = true
+		fv.to = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+		if ff.from != "" {
+			if fv.from, err = parseTime(ff.from); err != nil {
+				return fv, fmt.Errorf("invalid -from: %w", err)
+			}
+		}
+		if ff.to != "" {
+			if fv.to, err = parseTime(ff.to); err != nil {
+				return fv, fmt.Errorf("invalid -to: %w", err)
+			}
+		}
+	}
+
+	if len(ff.skip) > 0 {
+		fv.skipMap = make(map[string]struct{}, len(ff.skip))
+		for _, s := range ff.skip {
+			var entry, err = path.NewEntry(s, 0)
+			if err != nil {
+				fv.skipMap[s] = struct{}{}
+				continue
+			}
+			fv.skipMap[entry.AbsolutePath] = struct{}{}
+		}
+	}
+
+	if ff.minPerm != "" || ff.maxPerm != "" {
+		fv.hasPerm = true
+		fv.maxPerm = math.MaxUint32
+		if ff.minPerm != "" {
+			if fv.minPerm, err = parsePerm(ff.minPerm); err != nil {
+				return fv, fmt.Errorf("invalid -min-perm: %w", err)
+			}
+		}
+		if ff.maxPerm != "" {
+			if fv.maxPerm, err = parsePerm(ff.maxPerm); err != nil {
+				return fv, fmt.Errorf("invalid -max-perm: %w", err)
+			}
+		}
+	}
+
+	if ff.minSize >= 0 || ff.maxSize >= 0 {
+		fv.hasSize = true
+		fv.minSize = max(ff.minSize, 0)
+		fv.maxSize = math.MaxInt64
+		if ff.maxSize >= 0 {
+			fv.maxSize = ff.maxSize
+		}
+	}
+
+	if ff.fileType != "" && ff.fileType != "f" && ff.fileType != "d" {
+		return fv, fmt.Errorf("invalid -type: %s, must be f or d", ff.fileType)
+	}
+
+	return fv, nil
+}
+
+// entriesFilters builds the filters for ls and tree.
+func (ff *filterFlags) entriesFilters() ([]path.EntriesFilter, error) {
+	var fv, err = ff.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []path.EntriesFilter
+	if fv.regex != nil {
+		filters = append(filters, path.NewRegexEntitiesFilter(fv.regex))
+	}
+	if fv.hasDate {
+		filters = append(filters, path.NewDateEntitiesFilter(fv.from, fv.to))
+	}
+	if fv.skipMap != nil {
+		filters = append(filters, path.NewSkipMapEntitiesFilter(fv.skipMap))
+	}
+	if fv.hasPerm {
+		filters = append(filters, path.NewPermissionsEntitiesFilter(fv.minPerm, fv.maxPerm))
+	}
+	if fv.hasSize {
+		filters = append(filters, path.NewSizeEntitiesFilter(fv.minSize, fv.maxSize))
+	}
+	switch ff.fileType {
+	case "f":
+		filters = append(filters, path.NewFileEntitiesFilter())
+	case "d":
+		filters = append(filters, path.NewDirEntitiesFilter())
+	}
+	return filters, nil
+}
+
+// watchFilters builds the filters for watch.
+func (ff *filterFlags) watchFilters() ([]path.WatchFilter, error) {
+	var fv, err = ff.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []path.WatchFilter
+	if fv.regex != nil {
+		filters = append(filters, path.NewRegexWatchFilter(fv.regex))
+	}
+	if fv.hasDate {
+		filters = append(filters, path.NewDateWatchFilter(fv.from, fv.to))
+	}
+	if fv.skipMap != nil {
+		filters = append(filters, path.NewSkipMapWatchFilter(fv.skipMap))
+	}
+	if fv.hasPerm {
+		filters = append(filters, path.NewPermissionsWatchFilter(fv.minPerm, fv.maxPerm))
+	}
+	if fv.hasSize {
+		filters = append(filters, path.NewSizeWatchFilter(fv.minSize, fv.maxSize))
+	}
+	switch ff.fileType {
+	case "f":
+		filters = append(filters, path.NewFileWatchFilter())
+	case "d":
+		filters = append(filters, path.NewDirWatchFilter())
+	}
+	return filters, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+func parsePerm(s string) (uint32, error) {
+	var perm, err = strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(perm), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/kmulvey/path"
+)
+
+// ls lists every file matching the filters, one per line.
+func ls(name string, args []string, stdout, stderr io.Writer) error {
+	var fs = newFlagSet(name, stderr)
+	var filters filterFlags
+	filters.register(fs)
+	var depth = fs.Uint("depth", math.MaxUint8, "how many directory levels to descend (0-255)")
+	var includeRoot = fs.Bool("root", false, "include the input path itself in the results")
+	var long = fs.Bool("l", false, "long listing: mode, size, modification time and path")
+	var asJSON = fs.Bool("json", false, "print one JSON object per line")
+	var print0 = fs.Bool("0", false, "separate paths with NUL instead of newline, for xargs -0")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *depth > math.MaxUint8 {
+		return fmt.Errorf("-depth must be between 0 and %d", math.MaxUint8)
+	}
+
+	entriesFilters, err := filters.entriesFilters()
+	if err != nil {
+		return err
+	}
+
+	var encoder = json.NewEncoder(stdout)
+	for _, inputPath := range inputPaths(fs) {
+		// like find, a file given as input is listed itself
+		var withRoot = *includeRoot
+		if inputPath != path.StdinPath {
+			if entry, err := path.NewEntry(inputPath, 0); err == nil && !entry.IsDir() && len(entry.Children) == 0 {
+				withRoot = true
+			}
+		}
+
+		var files, err = path.List(inputPath, uint8(*depth), withRoot, entriesFilters...)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			switch {
+			case *asJSON:
+				file.Children = nil
+				if err := encoder.Encode(file); err != nil {
+					return err
+				}
+			case *long:
+				fmt.Fprintf(stdout, "%s %10d %s %s\n", file.FileInfo.Mode(), file.FileInfo.Size(), file.FileInfo.ModTime().Format("2006-01-02 15:04:05"), file.AbsolutePath)
+			case *print0:
+				fmt.Fprintf(stdout, "%s\x00", file.AbsolutePath)
+			default:
+				fmt.Fprintln(stdout, file.AbsolutePath)
+			}
+		}
+	}
+
+	return nil
+}
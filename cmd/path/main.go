@@ -0,0 +1,84 @@
+// Command path lists, prints and watches files using the same selection logic as the github.com/kmulvey/path package.
+//
+//	path ls    [flags] [path ...]   list matching files (alias: find)
+//	path tree  [flags] [path ...]   print matching files as a tree
+//	path watch [flags] [path ...]   print file system events as they happen
+//
+// Paths may be globs (quoted), file:// URIs, - to read a list of paths from stdin or @file to read them from a file.
+// watch -exec runs a shell command instead of printing events, e.g. path watch -regex '\.go$' -exec 'go test ./...'.
+// Run `path <command> -h` for the flags of each command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+const usage = `usage: path <command> [flags] [path ...]
+
+commands:
+  ls, find   list matching files
+  tree       print matching files as a tree
+  watch      print file system events as they happen
+
+run "path <command> -h" for the flags of a command
+`
+
+func main() {
+	var ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt)
+	var code = run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	os.Exit(code)
+}
+
+// run executes the command in args and returns the exit code. Long running commands stop when ctx is done.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "ls", "find":
+		err = ls(args[0], args[1:], stdout, stderr)
+	case "tree":
+		err = tree(args[1:], stdout, stderr)
+	case "watch":
+		err = watch(ctx, args[1:], stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", args[0], usage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintf(stderr, "path %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet creates a flag set for a command that reports errors to stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	var fs = flag.NewFlagSet("path "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// inputPaths returns the positional arguments or the current directory if there are none.
+func inputPaths(fs *flag.FlagSet) []string {
+	if fs.NArg() == 0 {
+		return []string{"."}
+	}
+	return fs.Args()
+}
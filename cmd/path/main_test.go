@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testdata = "../../testdata"
+
+// syncBuffer is a bytes.Buffer that can be written to by the watch goroutine while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+func runArgs(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	var code = run(t.Context(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	var code, _, stderr = runArgs(t)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage: path <command>")
+
+	code, _, stderr = runArgs(t, "nope")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown command: nope")
+
+	code, stdout, _ := runArgs(t, "help")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "commands:")
+
+	code, _, _ = runArgs(t, "ls", "-h")
+	assert.Equal(t, 0, code)
+}
+
+func TestLs(t *testing.T) {
+	t.Parallel()
+
+	var code, stdout, stderr = runArgs(t, "ls", testdata)
+	assert.Equal(t, 0, code, stderr)
+	assert.Len(t, lines(stdout), 8)
+
+	code, stdout, _ = runArgs(t, "find", "-type", "f", "-regex", `\.mp[34]$`, testdata)
+	assert.Equal(t, 0, code)
+	assert.Len(t, lines(stdout), 2)
+
+	code, stdout, _ = runArgs(t, "ls", "-type", "d", "-depth", "1", testdata)
+	assert.Equal(t, 0, code)
+	assert.Len(t, lines(stdout), 2)
+
+	code, stdout, _ = runArgs(t, "ls", "-min-size", "4000", "-max-size", "6000", "-type", "f", testdata)
+	assert.Equal(t, 0, code)
+	assert.Len(t, lines(stdout), 1)
+	assert.True(t, strings.HasSuffix(stdout, "file.mp4\n"))
+
+	code, stdout, _ = runArgs(t, "ls", "-0", "-skip", testdata+"/one/file.txt", testdata+"/one")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 3, strings.Count(stdout, "\x00"))
+
+	code, stdout, _ = runArgs(t, "ls", "-json", testdata+"/one")
+	assert.Equal(t, 0, code)
+	assert.Len(t, lines(stdout), 4)
+	assert.Contains(t, stdout, `"mtime"`)
+
+	code, stdout, _ = runArgs(t, "ls", "-l", testdata+"/one/file.mp4")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "file.mp4")
+
+	code, _, stderr = runArgs(t, "ls", "-type", "x", testdata)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid -type")
+
+	code, _, stderr = runArgs(t, "ls", "-from", "yesterday", testdata)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid -from")
+
+	code, _, stderr = runArgs(t, "ls", "-max-perm", "999", testdata)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid -max-perm")
+
+	code, _, stderr = runArgs(t, "ls", "-depth", "300", testdata)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "-depth must be between")
+
+	code, _, _ = runArgs(t, "ls", "./notexist")
+	assert.Equal(t, 1, code)
+}
+
+func TestTree(t *testing.T) {
+	t.Parallel()
+
+	var code, stdout, stderr = runArgs(t, "tree", "-regex", `\.txt$|one$|two$`, testdata)
+	assert.Equal(t, 0, code, stderr)
+
+	var expected = []string{
+		"├── one",
+		"│   └── file.txt",
+		"└── two",
+	}
+	assert.Equal(t, expected, lines(stdout)[1:])
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	var dir, other = t.TempDir(), t.TempDir()
+	var ctx, cancel = context.WithCancel(t.Context())
+	var stdout, stderr syncBuffer
+	var done = make(chan int)
+
+	go func() {
+		done <- run(ctx, []string{"watch", "-regex", `\.txt$`, "-op", "create", dir, other}, &stdout, &stderr)
+	}()
+
+	time.Sleep(time.Millisecond * 250) // give time for the watch to start up
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte{}, 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.mp3"), []byte{}, 0o600))
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, os.WriteFile(filepath.Join(other, "other.txt"), []byte{}, 0o600))
+
+	time.Sleep(time.Millisecond * 250) // give time for the watch to process events
+
+	cancel()
+	assert.Equal(t, 0, <-done)
+	assert.Equal(t, "CREATE "+filepath.Join(dir, "file.txt")+"\nCREATE "+filepath.Join(other, "other.txt")+"\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	var code, _, errOut = runArgs(t, "watch", "-op", "open", dir)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "invalid -op")
+
+	code, _, errOut = runArgs(t, "watch", "-prune", "[", dir)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "prune pattern")
+
+	code, _, errOut = runArgs(t, "watch", filepath.Join(dir, "notexist"))
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "does not exist")
+}
+
+func TestWatchExec(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+
+	var dir = t.TempDir()
+	var ctx, cancel = context.WithCancel(t.Context())
+	var stdout, stderr syncBuffer
+	var done = make(chan int)
+
+	go func() {
+		done <- run(ctx, []string{"watch", "-op", "create", "-exec", "echo start {}; sleep 10; echo end", "-restart", "-clear", dir}, &stdout, &stderr)
+	}()
+
+	time.Sleep(time.Millisecond * 250) // give time for the watch to start up
+
+	// files created together are one run
+	var one, two = filepath.Join(dir, "one.txt"), filepath.Join(dir, "two's.txt")
+	assert.NoError(t, os.WriteFile(one, []byte{}, 0o600))
+	assert.NoError(t, os.WriteFile(two, []byte{}, 0o600))
+	time.Sleep(time.Millisecond * 400)
+
+	// and a later change restarts the command
+	var three = filepath.Join(dir, "three.txt")
+	assert.NoError(t, os.WriteFile(three, []byte{}, 0o600))
+	time.Sleep(time.Millisecond * 400)
+
+	cancel()
+	select {
+	case code := <-done:
+		assert.Equal(t, 0, code)
+	case <-time.After(time.Second * 5):
+		assert.FailNow(t, "the command was not killed")
+	}
+	assert.Equal(t, clearScreen+"start "+one+" "+two+"\n"+clearScreen+"start "+three+"\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	var code, _, errOut = runArgs(t, "watch", "-restart", dir)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, errOut, "need -exec")
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package main
+
+import (
+	"os/exec"
+)
+
+// shellCommand runs command with cmd.exe.
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+// shellQuote quotes s for cmd.exe.
+func shellQuote(s string) string {
+	return `"` + s + `"`
+}
+
+// setProcessGroup does nothing, process groups are only available on unix.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd, the processes it started are left running.
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
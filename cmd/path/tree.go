@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/kmulvey/path"
+)
+
+// tree prints each input path and its matching children as a tree. Directories are always shown so the structure
+// leading to matching files is visible.
+func tree(args []string, stdout, stderr io.Writer) error {
+	var fs = newFlagSet("tree", stderr)
+	var filters filterFlags
+	filters.register(fs)
+	var depth = fs.Uint("depth", math.MaxUint8, "how many directory levels to descend (0-255)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *depth > math.MaxUint8 {
+		return fmt.Errorf("-depth must be between 0 and %d", math.MaxUint8)
+	}
+
+	entriesFilters, err := filters.entriesFilters()
+	if err != nil {
+		return err
+	}
+
+	for _, inputPath := range inputPaths(fs) {
+		var root, err = path.NewEntry(inputPath, uint8(*depth), entriesFilters...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(stdout, root.AbsolutePath)
+		printChildren(stdout, root, "")
+	}
+
+	return nil
+}
+
+// printChildren prints the children of entry sorted by name, each line prefixed with the branches of its parents.
+func printChildren(w io.Writer, entry path.Entry, prefix string) {
+	var children = entry.Children
+	sort.Slice(children, func(i, j int) bool { return children[i].AbsolutePath < children[j].AbsolutePath })
+
+	for i, child := range children {
+		var branch, indent = "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, filepath.Base(child.AbsolutePath))
+		printChildren(w, child, prefix+indent)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kmulvey/path"
+)
+
+// watch prints every matching event under the input paths, or runs the -exec command for them, until ctx is done.
+func watch(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var fs = newFlagSet("watch", stderr)
+	var filters filterFlags
+	filters.register(fs)
+	var depth = fs.Uint("depth", 0, "how many directory levels below the input path to watch (0-255)")
+	var ops = fs.String("op", "", "only report these events, comma separated: create,write,remove,rename,chmod")
+	var asJSON = fs.Bool("json", false, "print one JSON object per line")
+	var prune = fs.String("prune", "", "do not watch paths matching these patterns, comma separated, e.g. node_modules,.git")
+	var ignoreFile = fs.String("ignore-file", "", "read more -prune patterns from this file in each input path, e.g. .gitignore")
+	var onChange execFlags
+	fs.StringVar(&onChange.command, "exec", "", "run this shell command when something changes instead of printing events, {} is replaced by the changed paths")
+	fs.DurationVar(&onChange.debounce, "debounce", time.Millisecond*100, "with -exec, how long events have to stop before the command runs")
+	fs.BoolVar(&onChange.restart, "restart", false, "with -exec, kill the command if it is still running when something changes and run it again")
+	fs.BoolVar(&onChange.clear, "clear", false, "with -exec, clear the screen before each run")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *depth > math.MaxUint8 {
+		return fmt.Errorf("-depth must be between 0 and %d", math.MaxUint8)
+	}
+	if onChange.command == "" && (onChange.restart || onChange.clear) {
+		return fmt.Errorf("-restart and -clear need -exec")
+	}
+
+	watchFilters, err := filters.watchFilters()
+	if err != nil {
+		return err
+	}
+
+	if *ops != "" {
+		var opFilter, err = parseOps(*ops)
+		if err != nil {
+			return err
+		}
+		watchFilters = append(watchFilters, opFilter)
+	}
+
+	var opts = []path.WatchOption{path.WithFilters(watchFilters...), path.WithWatchDepth(uint8(*depth))}
+	if *prune != "" {
+		opts = append(opts, path.WithPrune(strings.Split(*prune, ",")...))
+	}
+	if *ignoreFile != "" {
+		opts = append(opts, path.WithIgnoreFile(*ignoreFile))
+	}
+
+	watcher, err := path.NewWatcher(ctx, uint8(*depth), opts...)
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, inputPath := range inputPaths(fs) {
+		if !strings.ContainsAny(inputPath, "*?[") {
+			if _, err := path.NewEntry(inputPath, 0, path.MustExist()); err != nil {
+				return err
+			}
+		}
+		if err := watcher.Add(inputPath); err != nil {
+			return err
+		}
+	}
+
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range watcher.Errors() {
+			fmt.Fprintf(stderr, "path watch: %s\n", err)
+		}
+	}()
+
+	if onChange.command != "" {
+		execOnChange(onChange, watcher.Events(), stdout, stderr)
+		<-done
+		return nil
+	}
+
+	var encoder = json.NewEncoder(stdout)
+	for event := range watcher.Events() {
+		if *asJSON {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(stdout, event)
+		}
+	}
+
+	<-done
+	return nil
+}
+
+// parseOps turns a comma separated list of operation names into an OpWatchFilter.
+func parseOps(s string) (path.OpWatchFilter, error) {
+	var ops []fsnotify.Op
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "create":
+			ops = append(ops, fsnotify.Create)
+		case "write":
+			ops = append(ops, fsnotify.Write)
+		case "remove":
+			ops = append(ops, fsnotify.Remove)
+		case "rename":
+			ops = append(ops, fsnotify.Rename)
+		case "chmod":
+			ops = append(ops, fsnotify.Chmod)
+		default:
+			return path.OpWatchFilter{}, fmt.Errorf("invalid -op: %s", name)
+		}
+	}
+	return path.NewOpWatchFilter(ops...), nil
+}
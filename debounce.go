@@ -0,0 +1,75 @@
+package path
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// debouncer coalesces bursts of events for the same path. An event is due once its path has been quiet for window,
+// it then carries the union of every Op seen during the burst.
+type debouncer struct {
+	window  time.Duration
+	pending map[string]*pendingEvent
+	seq     uint64
+}
+
+// pendingEvent is a coalesced event waiting for its path to go quiet.
+type pendingEvent struct {
+	event trackedEvent
+	last  time.Time
+	seq   uint64 // order of the first event of the burst, so due events keep arrival order
+}
+
+func newDebouncer(window time.Duration) *debouncer {
+	return &debouncer{window: window, pending: make(map[string]*pendingEvent)}
+}
+
+// add merges event into the pending burst for its path, or starts one.
+func (d *debouncer) add(event trackedEvent, now time.Time) {
+	if p, has := d.pending[event.Name]; has {
+		p.event.Op |= event.Op
+		if event.oldPath != "" { // the path was moved here during the burst
+			p.event.oldPath, p.event.newPath = event.oldPath, event.newPath
+		}
+		if event.info != nil { // the last known metadata of a path that went away during the burst
+			p.event.info = event.info
+		}
+		p.last = now
+		return
+	}
+
+	d.seq++
+	d.pending[event.Name] = &pendingEvent{event: event, last: now, seq: d.seq}
+}
+
+// due removes and returns every event that has been quiet for window, in the order their bursts started.
+func (d *debouncer) due(now time.Time) []trackedEvent {
+	var ready []*pendingEvent
+	for name, p := range d.pending {
+		if !now.Before(p.last.Add(d.window)) {
+			ready = append(ready, p)
+			delete(d.pending, name)
+		}
+	}
+
+	slices.SortFunc(ready, func(a, b *pendingEvent) int { return cmp.Compare(a.seq, b.seq) })
+
+	var events = make([]trackedEvent, len(ready))
+	for i, p := range ready {
+		events[i] = p.event
+	}
+	return events
+}
+
+// next returns when the earliest pending event will be due, false if nothing is pending.
+func (d *debouncer) next() (time.Time, bool) {
+	var earliest time.Time
+	for _, p := range d.pending {
+		var deadline = p.last.Add(d.window)
+		if earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
@@ -0,0 +1,155 @@
+package path
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTrackedEvent(name string, op fsnotify.Op) trackedEvent {
+	return trackedEvent{Event: fsnotify.Event{Name: name, Op: op}}
+}
+
+func TestDebouncer(t *testing.T) {
+	t.Parallel()
+
+	var start = time.Date(2022, 06, 01, 0, 0, 0, 0, time.UTC)
+	var d = newDebouncer(time.Second)
+
+	var _, pending = d.next()
+	assert.False(t, pending)
+
+	d.add(newTrackedEvent("a", fsnotify.Create), start)
+	d.add(newTrackedEvent("b", fsnotify.Create), start.Add(time.Millisecond*100))
+	d.add(newTrackedEvent("a", fsnotify.Write), start.Add(time.Millisecond*500))
+	d.add(newTrackedEvent("a", fsnotify.Chmod), start.Add(time.Millisecond*900))
+
+	next, pending := d.next()
+	assert.True(t, pending)
+	assert.Equal(t, start.Add(time.Millisecond*1100), next) // b is quiet first
+
+	assert.Empty(t, d.due(start.Add(time.Millisecond*1099)))
+
+	var due = d.due(start.Add(time.Millisecond * 1100))
+	assert.Equal(t, []trackedEvent{newTrackedEvent("b", fsnotify.Create)}, due)
+
+	// every write to a pushed it back
+	assert.Empty(t, d.due(start.Add(time.Millisecond*1899)))
+	due = d.due(start.Add(time.Millisecond * 1900))
+	assert.Equal(t, []trackedEvent{newTrackedEvent("a", fsnotify.Create|fsnotify.Write|fsnotify.Chmod)}, due)
+
+	_, pending = d.next()
+	assert.False(t, pending)
+
+	// a move during the burst is kept
+	d.add(newTrackedEvent("f", fsnotify.Write), start)
+	d.add(trackedEvent{Event: fsnotify.Event{Name: "f", Op: fsnotify.Rename}, oldPath: "g", newPath: "f"}, start)
+	due = d.due(start.Add(time.Second))
+	assert.Equal(t, []trackedEvent{{Event: fsnotify.Event{Name: "f", Op: fsnotify.Write | fsnotify.Rename}, oldPath: "g", newPath: "f"}}, due)
+
+	// due events keep the order their bursts started in
+	d.add(newTrackedEvent("c", fsnotify.Write), start)
+	d.add(newTrackedEvent("d", fsnotify.Write), start)
+	d.add(newTrackedEvent("e", fsnotify.Write), start)
+	due = d.due(start.Add(time.Hour))
+	assert.Len(t, due, 3)
+	assert.Equal(t, "c", due[0].Name)
+	assert.Equal(t, "d", due[1].Name)
+	assert.Equal(t, "e", due[2].Name)
+}
+
+func TestWatchDirDebounce(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 0, false, files, errs, WithDebounce(time.Second), WithClock(clock))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	var file = filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("one"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(file, []byte("two"), os.ModePerm))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to process events
+
+	// nothing is published until the path has been quiet for the window
+	select {
+	case event := <-files:
+		assert.Fail(t, "event published before the window passed", event.AbsolutePath)
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	var event = <-files
+	assert.Equal(t, file, event.AbsolutePath)
+	assert.True(t, event.Has(fsnotify.Create))
+	assert.True(t, event.Has(fsnotify.Write))
+
+	select {
+	case event := <-files:
+		assert.Fail(t, "burst was not coalesced", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	cancel()
+}
+
+func TestWatchDirDebounceRemove(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, make([]byte, 100), os.ModePerm))
+
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 0, false, files, errs, WithDebounce(time.Second), WithClock(clock), NewSizeWatchFilter(50, 200))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	// the burst is judged on what the file was when it went away
+	var f, err = os.OpenFile(file, os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	assert.NoError(t, err)
+	_, err = f.Write(make([]byte, 20))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	assert.NoError(t, os.Remove(file))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to process events
+	clock.Advance(time.Second)
+
+	select {
+	case event := <-files:
+		assert.Equal(t, file, event.AbsolutePath)
+		assert.True(t, event.Has(fsnotify.Remove))
+		assert.GreaterOrEqual(t, event.FileInfo.Size(), int64(100))
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "remove was dropped")
+	}
+
+	cancel()
+}
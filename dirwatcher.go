@@ -0,0 +1,485 @@
+package path
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// listedWindow is how long live events are matched against a listing published by publishListed. The events that
+// raced with the listing are queued in the watch backend by the time it is published, so they are seen well within it.
+const listedWindow = time.Second
+
+// dirWatcher is the state behind a Watcher. Events from the watch backend go through process, which keeps the watched
+// directories and metadata cache up to date, then queue, which holds them for the debouncer, and finally handle
+// which filters and publishes them.
+type dirWatcher struct {
+	config      watchConfig
+	watcher     watchBackend
+	dirs        *watchedDirs
+	cache       *metadataCache
+	debounce    *debouncer
+	renames     *renameTracker
+	writes      *writeTracker
+	files       chan WatchEvent
+	errors      chan error
+	done        <-chan struct{} // closed when the watch is stopped, sends give up then
+	requests    chan pathRequest
+	paths       map[string]struct{}    // paths and glob patterns given to Add
+	scaffold    map[string]struct{}    // directories watched only to see new glob matches, or missing paths, appear
+	listed      map[string]fs.FileInfo // paths published as synthetic creates that live events may repeat
+	listedUntil time.Time              // when listed is forgotten, zero when it is empty
+	saved       watchState             // loaded from the state file, roots are taken as they are added
+	counters    watchCounters
+	nextSave    time.Time
+}
+
+// pathRequest asks the run loop to start, or stop, watching path.
+type pathRequest struct {
+	path   string
+	remove bool
+	reply  chan error
+}
+
+// trackedEvent is an fsnotify.Event on its way through the watch pipeline.
+type trackedEvent struct {
+	fsnotify.Event
+	oldPath string      // set for a tracked rename
+	newPath string      // set for a tracked rename whose new name is under watch
+	info    fs.FileInfo // last known metadata of a path that no longer exists
+}
+
+func newDirWatcher(watcher watchBackend, recursiveDepth uint8, files chan WatchEvent, errors chan error, done <-chan struct{}, config watchConfig) *dirWatcher {
+	var dw = &dirWatcher{
+		config:   config,
+		watcher:  watcher,
+		dirs:     newWatchedDirs(watcher, config.depth(recursiveDepth)),
+		cache:    newMetadataCache(),
+		files:    files,
+		errors:   errors,
+		done:     done,
+		requests: make(chan pathRequest),
+		paths:    make(map[string]struct{}),
+		scaffold: make(map[string]struct{}),
+		listed:   make(map[string]fs.FileInfo),
+	}
+
+	if config.debounce > 0 {
+		dw.debounce = newDebouncer(config.debounce)
+	}
+	if config.renameWindow > 0 {
+		dw.renames = newRenameTracker(config.renameWindow)
+	}
+	if config.writeStable > 0 {
+		dw.writes = newWriteTracker(config.writeStable)
+	}
+	if config.stateFile != "" && config.stateInterval > 0 {
+		dw.nextSave = config.clock.Now().Add(config.stateInterval)
+	}
+	return dw
+}
+
+// add starts watching dir and everything below it within the watch depth.
+func (dw *dirWatcher) add(dir string) error {
+	var found, err = dw.dirs.add(dir)
+	for _, entry := range found {
+		dw.cache.set(entry.AbsolutePath, entry.FileInfo)
+	}
+	return err
+}
+
+// addPath starts watching path, a path or a glob pattern. The work is done by the run loop so that initial events
+// are published in step with live ones.
+func (dw *dirWatcher) addPath(path string) error {
+	return dw.request(pathRequest{path: path, reply: make(chan error, 1)})
+}
+
+// removePath stops watching a path given to addPath.
+func (dw *dirWatcher) removePath(path string) error {
+	return dw.request(pathRequest{path: path, remove: true, reply: make(chan error, 1)})
+}
+
+func (dw *dirWatcher) request(request pathRequest) error {
+	select {
+	case dw.requests <- request:
+	case <-dw.done:
+		return fsnotify.ErrClosed
+	}
+	return <-request.reply
+}
+
+// serve handles a pathRequest in the run loop.
+func (dw *dirWatcher) serve(request pathRequest) {
+	if request.remove {
+		request.reply <- dw.unregister(request.path)
+		return
+	}
+
+	dw.paths[request.path] = struct{}{}
+	if isGlob(request.path) || dw.config.missingPaths {
+		// the path is watched as soon as it exists
+		request.reply <- nil
+		dw.expandPaths(false)
+		return
+	}
+
+	var found, err = dw.addRoot(request.path)
+	request.reply <- err
+	if err == nil {
+		dw.announce(request.path, found)
+	}
+}
+
+// announce publishes what is already in a root that was just added: everything with WithInitialEvents, and what
+// changed since the last run with WithStateFile.
+func (dw *dirWatcher) announce(root string, found []Entry) {
+	if dw.config.initialEvents {
+		dw.publishListed(found)
+	}
+	if dw.config.stateFile != "" {
+		dw.catchUp(root, found)
+	}
+}
+
+// addRoot watches root and returns what is already in it, including root itself when it is a file. The watches are
+// in place before each directory is listed, so anything created meanwhile is either listed, seen live, or both;
+// publishListed and process make sure it is published once.
+func (dw *dirWatcher) addRoot(root string) ([]Entry, error) {
+	var found, err = dw.dirs.addRoot(root)
+	for _, entry := range found {
+		dw.cache.set(entry.AbsolutePath, entry.FileInfo)
+	}
+
+	if info, err := os.Lstat(root); err == nil && !info.IsDir() {
+		dw.cache.set(root, info)
+		found = append([]Entry{{AbsolutePath: root, FileInfo: info}}, found...)
+	}
+	return found, err
+}
+
+// publishListed queues a create event for each entry and remembers them for listedWindow so a live create for the
+// same file is dropped.
+func (dw *dirWatcher) publishListed(entries []Entry) {
+	for _, entry := range entries {
+		dw.listed[entry.AbsolutePath] = entry.FileInfo
+		dw.queue(trackedEvent{Event: fsnotify.Event{Name: entry.AbsolutePath, Op: fsnotify.Create}})
+	}
+	if len(entries) > 0 {
+		dw.listedUntil = dw.config.clock.Now().Add(listedWindow)
+	}
+}
+
+// unregister forgets path and stops watching every root only it accounted for.
+func (dw *dirWatcher) unregister(path string) error {
+	if _, has := dw.paths[path]; !has {
+		return fmt.Errorf("%w: %s", fsnotify.ErrNonExistentWatch, path)
+	}
+	delete(dw.paths, path)
+
+	for _, root := range dw.dirs.rootList() {
+		if dw.claimed(root) {
+			continue
+		}
+		dw.dirs.removeRoot(root)
+		if dw.dirs.depth(root) < 0 { // not within another root
+			dw.cache.remove(root)
+		}
+	}
+
+	dw.pruneScaffold()
+	return nil
+}
+
+// claimed reports if root was given to Add or matches a glob pattern that was.
+func (dw *dirWatcher) claimed(root string) bool {
+	for path := range dw.paths {
+		if path == root {
+			return true
+		} else if matched, _ := filepath.Match(path, root); matched && isGlob(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// run processes events until done is closed or the watch backend is closed.
+func (dw *dirWatcher) run() {
+	for {
+		select {
+		case <-dw.done:
+			return
+
+		case <-dw.timer():
+			dw.flush()
+
+		case request := <-dw.requests:
+			dw.serve(request)
+
+		case event, open := <-dw.watcher.Events():
+			if !open {
+				return
+			}
+			dw.process(event)
+
+		case err, open := <-dw.watcher.Errors():
+			if !open {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				dw.rescan()
+				continue
+			}
+			dw.sendError(err)
+		}
+	}
+}
+
+// timer fires when the next held event is due, it is nil when nothing is held.
+func (dw *dirWatcher) timer() <-chan time.Time {
+	var deadline time.Time
+	if dw.debounce != nil {
+		deadline, _ = dw.debounce.next()
+	}
+	if dw.renames != nil {
+		if next, ok := dw.renames.next(); ok && (deadline.IsZero() || next.Before(deadline)) {
+			deadline = next
+		}
+	}
+	if dw.writes != nil {
+		if next, ok := dw.writes.next(); ok && (deadline.IsZero() || next.Before(deadline)) {
+			deadline = next
+		}
+	}
+	if !dw.nextSave.IsZero() && (deadline.IsZero() || dw.nextSave.Before(deadline)) {
+		deadline = dw.nextSave
+	}
+	if !dw.listedUntil.IsZero() && (deadline.IsZero() || dw.listedUntil.Before(deadline)) {
+		deadline = dw.listedUntil
+	}
+
+	if deadline.IsZero() {
+		return nil
+	}
+	return dw.config.clock.After(deadline.Sub(dw.config.clock.Now()))
+}
+
+// flush publishes every held event that is due.
+func (dw *dirWatcher) flush() {
+	var now = dw.config.clock.Now()
+
+	if dw.renames != nil {
+		// these were moved out of the watch
+		for _, p := range dw.renames.expired(now) {
+			dw.cache.remove(p.path)
+			dw.queue(trackedEvent{Event: fsnotify.Event{Name: p.path, Op: fsnotify.Rename}, oldPath: p.path, info: p.info})
+		}
+	}
+
+	if dw.writes != nil {
+		for _, event := range dw.writes.due(now, os.Lstat) {
+			dw.release(event)
+		}
+	}
+
+	if dw.debounce != nil {
+		for _, event := range dw.debounce.due(now) {
+			dw.handle(event)
+		}
+	}
+
+	if !dw.nextSave.IsZero() && !now.Before(dw.nextSave) {
+		if err := dw.saveState(); err != nil {
+			dw.sendError(err)
+		}
+		dw.nextSave = now.Add(dw.config.stateInterval)
+	}
+
+	if !dw.listedUntil.IsZero() && !now.Before(dw.listedUntil) {
+		clear(dw.listed)
+		dw.listedUntil = time.Time{}
+	}
+}
+
+// process keeps the watched directories and metadata cache in step with event and queues it for publishing.
+func (dw *dirWatcher) process(event fsnotify.Event) {
+
+	dw.followScaffold(event)
+
+	// the scaffold and overlapping watches report paths that are outside every root or already gone, pruned paths
+	// are never reported
+	if depth := dw.dirs.depth(event.Name); depth < 0 || depth > int(dw.dirs.maxDepth)+1 {
+		return
+	} else if (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) && dw.dirs.isRoot(event.Name) && !dw.dirs.has(event.Name) {
+		return
+	} else if dw.dirs.pruned(event.Name, func() bool { return dw.isDir(event.Name) }) {
+		return
+	}
+
+	if listed, has := dw.listed[event.Name]; has {
+		delete(dw.listed, event.Name)
+		if event.Op == fsnotify.Create {
+			if info, err := os.Lstat(event.Name); err != nil || os.SameFile(listed, info) {
+				return // already published by the initial listing
+			}
+		}
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		var info, _ = dw.cache.get(event.Name)
+		dw.dirs.remove(event.Name)
+
+		// hold the old name until the new one shows up
+		if dw.renames != nil && event.Has(fsnotify.Rename) {
+			dw.renames.renamed(event.Name, info, dw.config.clock.Now())
+			return
+		}
+
+		dw.cache.remove(event.Name)
+		dw.queue(trackedEvent{Event: event, info: info})
+		return
+	}
+
+	var info, err = os.Lstat(event.Name)
+	if err != nil { // already gone, a remove event will follow
+		dw.queue(trackedEvent{Event: event})
+		return
+	}
+	dw.cache.set(event.Name, info)
+
+	if !event.Has(fsnotify.Create) {
+		dw.queue(trackedEvent{Event: event})
+		return
+	}
+
+	if dw.renames != nil {
+		if p, moved := dw.renames.created(info); moved {
+			dw.cache.move(p.path, event.Name)
+			dw.cache.set(event.Name, info)
+			if info.IsDir() {
+				if err := dw.add(event.Name); err != nil {
+					dw.sendError(err)
+				}
+			}
+
+			dw.queue(trackedEvent{Event: fsnotify.Event{Name: event.Name, Op: fsnotify.Rename}, oldPath: p.path, newPath: event.Name})
+			return
+		}
+	}
+
+	dw.queue(trackedEvent{Event: event})
+
+	if !info.IsDir() {
+		return
+	}
+
+	// anything already in a new directory was created before the watch could see it
+	found, err := dw.dirs.add(event.Name)
+	if err != nil {
+		dw.sendError(err)
+	}
+	for _, entry := range found {
+		dw.cache.set(entry.AbsolutePath, entry.FileInfo)
+		dw.queue(trackedEvent{Event: fsnotify.Event{Name: entry.AbsolutePath, Op: fsnotify.Create}})
+	}
+}
+
+// isDir reports if path is a directory, or was one before it was removed.
+func (dw *dirWatcher) isDir(path string) bool {
+	if info, has := dw.cache.get(path); has {
+		return info.IsDir()
+	}
+	var info, err = os.Lstat(path)
+	return err == nil && info.IsDir()
+}
+
+// queue holds event until its file is written or for the debouncer, or publishes it straight away.
+func (dw *dirWatcher) queue(event trackedEvent) {
+	if dw.writes != nil {
+		if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+			dw.writes.forget(event.Name)
+		} else if info, has := dw.cache.get(event.Name); has && !info.IsDir() && dw.writes.add(event, info, dw.config.clock.Now()) {
+			return
+		}
+	}
+	dw.release(event)
+}
+
+// release holds event for the debouncer or publishes it straight away.
+func (dw *dirWatcher) release(event trackedEvent) {
+	if dw.debounce != nil {
+		dw.debounce.add(event, dw.config.clock.Now())
+		return
+	}
+	dw.handle(event)
+}
+
+// handle runs an event through the filters and publishes it. Events that are only a fsnotify.Chmod are dropped
+// first, unless WithChmodEvents, then the filters on path and Op run and only then the metadata is read.
+func (dw *dirWatcher) handle(event trackedEvent) {
+	if event.Op == fsnotify.Chmod && !dw.config.chmodEvents {
+		return
+	}
+
+	var watchEvent = WatchEvent{Entry: Entry{AbsolutePath: event.Name}, Op: event.Op, OldPath: event.oldPath, NewPath: event.newPath}
+	if !dw.accepts(watchEvent, false) {
+		return
+	}
+
+	// renamed and removed paths no longer exist, describe them from the cache
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) && event.newPath == "" {
+		watchEvent.FileInfo = event.info
+	} else if info, has := dw.cache.get(event.Name); has {
+		watchEvent.FileInfo = info
+	} else if info, err := os.Lstat(event.Name); err != nil {
+		dw.sendError(fmt.Errorf("error stating file: %s, error: %w", event.Name, err))
+		return
+	} else {
+		watchEvent.FileInfo = info
+	}
+
+	if !dw.accepts(watchEvent, true) {
+		return
+	}
+	if watchEvent.FileInfo == nil {
+		watchEvent.FileInfo = fileInfo{name: filepath.Base(event.Name)}
+	}
+
+	select {
+	case dw.files <- watchEvent:
+	case <-dw.done:
+	}
+}
+
+// accepts runs the filters on metadata, or the others, on event. Events without metadata are not accepted by filters
+// on metadata.
+func (dw *dirWatcher) accepts(event WatchEvent, metadata bool) bool {
+	for _, fn := range dw.config.filters {
+		if _, ok := fn.(metadataWatchFilter); ok != metadata {
+			continue
+		} else if metadata && event.FileInfo == nil {
+			return false
+		}
+
+		var accepted, err = fn.filter(event)
+		if err != nil {
+			dw.sendError(err)
+		}
+		if !accepted {
+			return false
+		}
+	}
+	return true
+}
+
+// sendError publishes err unless the watch is stopped.
+func (dw *dirWatcher) sendError(err error) {
+	select {
+	case dw.errors <- err:
+	case <-dw.done:
+	}
+}
@@ -0,0 +1,183 @@
+package path
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultShardQueue is how many events a Dispatcher queues per shard, see WithShardQueue.
+const defaultShardQueue = 1024
+
+// ShardKey picks the shard of an event, a Dispatcher handles the events of one shard one at a time and in the order
+// they were dispatched.
+type ShardKey func(event WatchEvent) string
+
+// ShardByPath puts every path in its own shard, it is the default.
+func ShardByPath(event WatchEvent) string {
+	return event.AbsolutePath
+}
+
+// ShardByTopLevelDir puts everything in a directory directly below root, and that directory itself, in one shard.
+// Files directly in root and paths outside of it are sharded by path.
+func ShardByTopLevelDir(root string) ShardKey {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+
+	return func(event WatchEvent) string {
+		var rel, err = filepath.Rel(root, event.AbsolutePath)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return event.AbsolutePath
+		}
+		var top, _, _ = strings.Cut(rel, string(filepath.Separator))
+		return filepath.Join(root, top)
+	}
+}
+
+// Dispatcher runs a handler for events on a bounded number of goroutines. Events are sharded with a ShardKey, events
+// of the same shard are handled one at a time and in order while different shards are handled in parallel, so a
+// Write is never handled after the Remove that followed it. The number of workers is set with WithWorkers, the
+// ShardKey with WithShardKey, how many events wait per shard with WithShardQueue and a failing handler is retried as
+// configured with WithRetry. Once a handler still fails after its retries the Dispatcher stops, events that are
+// waiting are dropped and Wait returns the error.
+type Dispatcher struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	handler func(WatchEvent) error
+	config  watchConfig
+	slots   chan struct{} // one per running worker
+	wg      sync.WaitGroup
+	mu      sync.Mutex              // guards pending and err
+	queued  *sync.Cond              // signalled when a queued event is taken or the Dispatcher stops
+	pending map[string][]WatchEvent // shards with a worker, and the events waiting behind it
+	err     error
+}
+
+// NewDispatcher creates a Dispatcher for handler, it stops when ctx is done. Options other than WithWorkers,
+// WithShardKey, WithShardQueue, WithRetry and WithClock are ignored.
+func NewDispatcher(ctx context.Context, handler func(WatchEvent) error, opts ...WatchOption) *Dispatcher {
+	var config = newWatchConfig(opts...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	var d = &Dispatcher{
+		ctx:     ctx,
+		cancel:  cancel,
+		handler: handler,
+		config:  config,
+		slots:   make(chan struct{}, config.workers),
+		pending: make(map[string][]WatchEvent),
+	}
+	d.queued = sync.NewCond(&d.mu)
+	context.AfterFunc(ctx, func() {
+		d.mu.Lock()
+		d.queued.Broadcast()
+		d.mu.Unlock()
+	})
+	return d
+}
+
+// Dispatch queues event behind the events of its shard that are being handled, or starts a worker for it. It blocks
+// while every worker is busy or the queue of its shard is full, so a handler must not dispatch to its own shard.
+// Events are dropped once the Dispatcher is stopped.
+func (d *Dispatcher) Dispatch(event WatchEvent) {
+	if d.ctx.Err() != nil {
+		return
+	}
+	var key = d.config.shardKey(event)
+
+	d.mu.Lock()
+	for queue, running := d.pending[key]; running; queue, running = d.pending[key] {
+		if len(queue) < d.config.shardQueue {
+			d.pending[key] = append(queue, event)
+			d.mu.Unlock()
+			return
+		}
+		d.queued.Wait()
+		if d.ctx.Err() != nil {
+			d.mu.Unlock()
+			return
+		}
+	}
+	d.pending[key] = nil
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	select {
+	case d.slots <- struct{}{}:
+		go d.work(key, event)
+	case <-d.ctx.Done():
+		d.mu.Lock()
+		delete(d.pending, key)
+		d.mu.Unlock()
+		d.wg.Done()
+	}
+}
+
+// Wait blocks until every dispatched event has been handled, or dropped after the Dispatcher stopped, and returns
+// the error of the handler that stopped it. Events must not be dispatched while waiting.
+func (d *Dispatcher) Wait() error {
+	d.wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Stop stops the Dispatcher, running handlers are not interrupted.
+func (d *Dispatcher) Stop() {
+	d.cancel()
+}
+
+// work handles event and then the events queued for its shard until there are none left.
+func (d *Dispatcher) work(key string, event WatchEvent) {
+	defer d.wg.Done()
+	defer func() { <-d.slots }()
+
+	for {
+		if err := d.handle(event); err != nil {
+			d.fail(fmt.Errorf("error handling %s: %w", event.AbsolutePath, err))
+		}
+
+		d.mu.Lock()
+		var queue = d.pending[key]
+		if len(queue) == 0 || d.ctx.Err() != nil {
+			delete(d.pending, key)
+			d.queued.Broadcast()
+			d.mu.Unlock()
+			return
+		}
+		event, d.pending[key] = queue[0], queue[1:]
+		d.queued.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// handle calls the handler for event, and again after a backoff that doubles each time for as long as it fails and
+// retries are left.
+func (d *Dispatcher) handle(event WatchEvent) error {
+	var backoff = d.config.retryBackoff
+	var err = d.handler(event)
+	for retry := 0; err != nil && retry < d.config.retries; retry++ {
+		select {
+		case <-d.config.clock.After(backoff):
+		case <-d.ctx.Done():
+			return err
+		}
+		backoff *= 2
+		err = d.handler(event)
+	}
+	return err
+}
+
+// fail records the first error and stops the Dispatcher.
+func (d *Dispatcher) fail(err error) {
+	d.mu.Lock()
+	if d.err == nil {
+		d.err = err
+	}
+	d.mu.Unlock()
+	d.cancel()
+}
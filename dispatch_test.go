@@ -0,0 +1,168 @@
+package path
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Parallel()
+
+	var ctx = t.Context()
+	var mu sync.Mutex
+	var handled = make(map[string][]fsnotify.Op)
+	var running = make(map[string]bool)
+	var active, most atomic.Int32
+	var release = make(chan struct{})
+
+	var handler = func(event WatchEvent) error {
+		mu.Lock()
+		assert.False(t, running[event.AbsolutePath], "two handlers for %s", event.AbsolutePath)
+		running[event.AbsolutePath] = true
+		mu.Unlock()
+
+		var now = active.Add(1)
+		for {
+			var seen = most.Load()
+			if now <= seen || most.CompareAndSwap(seen, now) {
+				break
+			}
+		}
+		<-release
+		active.Add(-1)
+
+		mu.Lock()
+		running[event.AbsolutePath] = false
+		handled[event.AbsolutePath] = append(handled[event.AbsolutePath], event.Op)
+		mu.Unlock()
+		return nil
+	}
+
+	var dispatcher = NewDispatcher(ctx, handler, WithWorkers(2))
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Create})
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Write})
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Remove})
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/b"}, Op: fsnotify.Create})
+
+	// /a and /b are handled at the same time
+	for active.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.NoError(t, dispatcher.Wait())
+
+	assert.Equal(t, map[string][]fsnotify.Op{
+		"/a": {fsnotify.Create, fsnotify.Write, fsnotify.Remove},
+		"/b": {fsnotify.Create},
+	}, handled)
+	assert.Equal(t, int32(2), most.Load())
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	t.Parallel()
+
+	var clock = newFakeClock()
+	var ctx = t.Context()
+	var calls atomic.Int32
+	var handler = func(event WatchEvent) error {
+		if calls.Add(1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	}
+
+	// the third try succeeds, after waiting one and then two seconds
+	var dispatcher = NewDispatcher(ctx, handler, WithRetry(2, time.Second), WithClock(clock))
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}})
+	advancePoll(clock, time.Second)
+	advancePoll(clock, time.Second*2)
+	assert.NoError(t, dispatcher.Wait())
+	assert.Equal(t, int32(3), calls.Load())
+
+	// and one retry is not enough
+	calls.Store(0)
+	dispatcher = NewDispatcher(ctx, handler, WithRetry(1, time.Second), WithClock(clock))
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}})
+	advancePoll(clock, time.Second)
+	assert.EqualError(t, dispatcher.Wait(), "error handling /a: not yet")
+
+	// a stopped Dispatcher drops events
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/b"}})
+	assert.EqualError(t, dispatcher.Wait(), "error handling /a: not yet")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDispatcherShardQueue(t *testing.T) {
+	t.Parallel()
+
+	var handled atomic.Int32
+	var release = make(chan struct{})
+	var handler = func(event WatchEvent) error {
+		<-release
+		handled.Add(1)
+		return nil
+	}
+
+	// one event is handled and one waits, the third blocks until there is room
+	var dispatcher = NewDispatcher(t.Context(), handler, WithShardQueue(1))
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Create})
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Write})
+
+	var dispatched = make(chan struct{})
+	go func() {
+		dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Remove})
+		close(dispatched)
+	}()
+
+	select {
+	case <-dispatched:
+		assert.Fail(t, "dispatched to a full queue")
+	case <-time.After(time.Millisecond * 100):
+	}
+	close(release)
+	<-dispatched
+	assert.NoError(t, dispatcher.Wait())
+	assert.Equal(t, int32(3), handled.Load())
+
+	// stopping gives up a blocked Dispatch
+	release = make(chan struct{})
+	dispatcher = NewDispatcher(t.Context(), handler, WithShardQueue(1))
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Create})
+	dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Write})
+
+	dispatched = make(chan struct{})
+	go func() {
+		dispatcher.Dispatch(WatchEvent{Entry: Entry{AbsolutePath: "/a"}, Op: fsnotify.Remove})
+		close(dispatched)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	dispatcher.Stop()
+	<-dispatched
+	close(release)
+	assert.NoError(t, dispatcher.Wait())
+	assert.Equal(t, int32(4), handled.Load())
+}
+
+func TestShardByTopLevelDir(t *testing.T) {
+	t.Parallel()
+
+	var root = filepath.Join(string(filepath.Separator), "data")
+	var key = ShardByTopLevelDir(root)
+
+	var event = func(path ...string) WatchEvent {
+		return WatchEvent{Entry: Entry{AbsolutePath: filepath.Join(path...)}}
+	}
+	assert.Equal(t, filepath.Join(root, "one"), key(event(root, "one")))
+	assert.Equal(t, filepath.Join(root, "one"), key(event(root, "one", "two", "file.txt")))
+	assert.Equal(t, filepath.Join(root, "file.txt"), key(event(root, "file.txt")))
+	assert.Equal(t, root, key(event(root)))
+	assert.Equal(t, filepath.Join(string(filepath.Separator), "other", "file.txt"), key(event(string(filepath.Separator), "other", "file.txt")))
+	assert.Equal(t, filepath.Join(root, "..data"), key(event(root, "..data", "file.txt")))
+}
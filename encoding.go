@@ -0,0 +1,169 @@
+package path
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// entryJSON is the serialised form of an Entry.
+type entryJSON struct {
+	Path     string      `json:"path"`
+	Size     int64       `json:"size"`
+	Mode     fs.FileMode `json:"mode"`
+	ModTime  time.Time   `json:"mtime"`
+	IsDir    bool        `json:"dir"`
+	Children []Entry     `json:"children,omitempty"`
+}
+
+// MarshalText fulfils the encoding.TextMarshaler interface, the text form of an Entry is its absolute path.
+func (e Entry) MarshalText() ([]byte, error) {
+	return []byte(e.AbsolutePath), nil
+}
+
+// UnmarshalText fulfils the encoding.TextUnmarshaler interface so an Entry can be decoded from config files and
+// environment variables. The text is handled exactly like a flag value, see Set.
+func (e *Entry) UnmarshalText(text []byte) error {
+	return e.Set(string(text))
+}
+
+// MarshalJSON fulfils the json.Marshaler interface. Entries are encoded as an object with the path, size, mode,
+// modification time and children.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	var ej = entryJSON{Path: e.AbsolutePath, Children: e.Children}
+	if e.FileInfo != nil {
+		ej.Size = e.FileInfo.Size()
+		ej.Mode = e.FileInfo.Mode()
+		ej.ModTime = e.FileInfo.ModTime()
+		ej.IsDir = e.FileInfo.IsDir()
+	}
+	return json.Marshal(ej)
+}
+
+// UnmarshalJSON fulfils the json.Unmarshaler interface. A JSON string is treated as a path and stat'd like a flag
+// value, a JSON object as produced by MarshalJSON is restored as is without touching the filesystem.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '"' {
+		var inputPath string
+		if err := json.Unmarshal(data, &inputPath); err != nil {
+			return fmt.Errorf("error decoding entry path: %w", err)
+		}
+		return e.Set(inputPath)
+	}
+
+	var ej entryJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return fmt.Errorf("error decoding entry: %w", err)
+	}
+
+	e.AbsolutePath = ej.Path
+	e.Children = ej.Children
+	e.FileInfo = fileInfo{
+		name:    filepath.Base(ej.Path),
+		size:    ej.Size,
+		mode:    ej.Mode,
+		modTime: ej.ModTime,
+	}
+	return nil
+}
+
+// watchEventJSON is the serialised form of a WatchEvent.
+type watchEventJSON struct {
+	Op      string `json:"op"`
+	Entry   Entry  `json:"entry"`
+	OldPath string `json:"old_path,omitempty"`
+	NewPath string `json:"new_path,omitempty"`
+}
+
+// opNames are the names WatchEvent.OpString gives each Op.
+var opNames = map[string]fsnotify.Op{
+	"CREATE":   fsnotify.Create,
+	"WRITE":    fsnotify.Write,
+	"REMOVE":   fsnotify.Remove,
+	"RENAME":   fsnotify.Rename,
+	"CHMOD":    fsnotify.Chmod,
+	"MODIFIED": Modified,
+	"READY":    Ready,
+}
+
+// parseOp is the reverse of WatchEvent.OpString.
+func parseOp(text string) (fsnotify.Op, error) {
+	var op fsnotify.Op
+	if text == op.String() {
+		return op, nil
+	}
+	for _, name := range strings.Split(text, "|") {
+		var known, has = opNames[name]
+		if !has {
+			return op, fmt.Errorf("unknown op: %s", name)
+		}
+		op |= known
+	}
+	return op, nil
+}
+
+// MarshalText fulfils the encoding.TextMarshaler interface, the text form of a WatchEvent is its String. It replaces
+// the MarshalText of the embedded Entry, which would leave out the Op.
+func (we WatchEvent) MarshalText() ([]byte, error) {
+	return []byte(we.String()), nil
+}
+
+// UnmarshalText fulfils the encoding.TextUnmarshaler interface. Unlike an Entry the path is not stat'd, it may be
+// long gone, only its name is restored.
+func (we *WatchEvent) UnmarshalText(text []byte) error {
+	var ops, eventPath, found = strings.Cut(string(text), " ")
+	if !found {
+		return fmt.Errorf("error decoding watch event: %s", text)
+	}
+
+	var op, err = parseOp(ops)
+	if err != nil {
+		return fmt.Errorf("error decoding watch event: %w", err)
+	}
+	*we = WatchEvent{Entry: Entry{AbsolutePath: eventPath, FileInfo: fileInfo{name: filepath.Base(eventPath)}}, Op: op}
+	return nil
+}
+
+// MarshalJSON fulfils the json.Marshaler interface. A WatchEvent is encoded as an object with its Op, Entry and
+// OldPath and NewPath when they are set, the MarshalJSON of the embedded Entry would leave them out.
+func (we WatchEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(watchEventJSON{Op: we.OpString(), Entry: we.Entry, OldPath: we.OldPath, NewPath: we.NewPath})
+}
+
+// UnmarshalJSON fulfils the json.Unmarshaler interface, it restores a WatchEvent produced by MarshalJSON.
+func (we *WatchEvent) UnmarshalJSON(data []byte) error {
+	var wej watchEventJSON
+	if err := json.Unmarshal(data, &wej); err != nil {
+		return fmt.Errorf("error decoding watch event: %w", err)
+	}
+
+	var op, err = parseOp(wej.Op)
+	if err != nil {
+		return fmt.Errorf("error decoding watch event: %w", err)
+	}
+	*we = WatchEvent{Entry: wej.Entry, Op: op, OldPath: wej.OldPath, NewPath: wej.NewPath}
+	return nil
+}
+
+// fileInfo is a static fs.FileInfo used to restore decoded entries.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
@@ -0,0 +1,126 @@
+package path
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEntryText(t *testing.T) {
+	t.Parallel()
+
+	var entry, err = NewEntry("./testdata/one", 1)
+	assert.NoError(t, err)
+
+	text, err := entry.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, entry.AbsolutePath, string(text))
+
+	var decoded Entry
+	assert.NoError(t, decoded.UnmarshalText(text))
+	assert.Equal(t, entry.AbsolutePath, decoded.AbsolutePath)
+	assert.Len(t, decoded.Children, 4)
+
+	assert.Error(t, decoded.UnmarshalText([]byte("./testdata/notexist")))
+}
+
+func TestEntryConfig(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Input Entry `json:"input" yaml:"input"`
+	}
+
+	var jsonConfig config
+	assert.NoError(t, json.Unmarshal([]byte(`{"input": "./testdata/one"}`), &jsonConfig))
+	assert.True(t, prefixRegex.MatchString(jsonConfig.Input.AbsolutePath))
+	assert.True(t, jsonConfig.Input.IsDir())
+	assert.Len(t, jsonConfig.Input.Children, 4)
+
+	var yamlConfig config
+	assert.NoError(t, yaml.Unmarshal([]byte("input: ./testdata/one/file.txt\n"), &yamlConfig))
+	assert.True(t, prefixRegex.MatchString(yamlConfig.Input.AbsolutePath))
+	assert.False(t, yamlConfig.Input.IsDir())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"input": "./testdata/notexist"}`), &jsonConfig))
+}
+
+func TestEntryJSON(t *testing.T) {
+	t.Parallel()
+
+	var entry, err = NewEntry("./testdata/one", 1)
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(entry)
+	assert.NoError(t, err)
+
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, entry.AbsolutePath, fields["path"])
+	assert.Equal(t, true, fields["dir"])
+	assert.Len(t, fields["children"], 4)
+	assert.Contains(t, fields, "size")
+	assert.Contains(t, fields, "mode")
+	assert.Contains(t, fields, "mtime")
+
+	var decoded Entry
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, entry.AbsolutePath, decoded.AbsolutePath)
+	assert.Equal(t, entry.FileInfo.Mode(), decoded.FileInfo.Mode())
+	assert.Equal(t, entry.FileInfo.Name(), decoded.FileInfo.Name())
+	assert.True(t, entry.FileInfo.ModTime().Equal(decoded.FileInfo.ModTime()))
+	assert.True(t, decoded.IsDir())
+	assert.Len(t, decoded.Children, 4)
+
+	for i, child := range entry.Children {
+		assert.Equal(t, child.AbsolutePath, decoded.Children[i].AbsolutePath)
+		assert.Equal(t, child.FileInfo.Size(), decoded.Children[i].FileInfo.Size())
+	}
+
+	// an empty entry only has a path
+	data, err = json.Marshal(Entry{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"path":""`)
+}
+
+func TestWatchEventEncoding(t *testing.T) {
+	t.Parallel()
+
+	var entry, err = NewEntry("./testdata/one/file.txt", 0)
+	assert.NoError(t, err)
+	var event = WatchEvent{Entry: entry, Op: fsnotify.Rename | Modified, OldPath: "/tmp/old.txt", NewPath: entry.AbsolutePath}
+
+	data, err := json.Marshal(event)
+	assert.NoError(t, err)
+
+	var fields map[string]any
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "RENAME|MODIFIED", fields["op"])
+	assert.Equal(t, "/tmp/old.txt", fields["old_path"])
+	assert.Equal(t, entry.AbsolutePath, fields["new_path"])
+	assert.Equal(t, entry.AbsolutePath, fields["entry"].(map[string]any)["path"])
+
+	var decoded WatchEvent
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, event.Op, decoded.Op)
+	assert.Equal(t, event.OldPath, decoded.OldPath)
+	assert.Equal(t, event.NewPath, decoded.NewPath)
+	assert.Equal(t, event.AbsolutePath, decoded.AbsolutePath)
+	assert.Equal(t, event.FileInfo.Size(), decoded.FileInfo.Size())
+
+	text, err := event.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "RENAME|MODIFIED "+entry.AbsolutePath, string(text))
+
+	decoded = WatchEvent{}
+	assert.NoError(t, decoded.UnmarshalText([]byte("REMOVE /tmp/gone.txt")))
+	assert.Equal(t, fsnotify.Remove, decoded.Op)
+	assert.Equal(t, "/tmp/gone.txt", decoded.AbsolutePath)
+	assert.Equal(t, "gone.txt", decoded.FileInfo.Name())
+
+	assert.Error(t, decoded.UnmarshalText([]byte("EXPLODE /tmp/gone.txt")))
+	assert.Error(t, json.Unmarshal([]byte(`{"op":"EXPLODE"}`), &decoded))
+}
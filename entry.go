@@ -14,19 +14,45 @@ type Entry struct {
 	FileInfo     fs.FileInfo
 	AbsolutePath string
 	Children     []Entry
+	validators   []EntryValidator
 }
 
 // NewEntry is the public constructor for creating an Entry. The levelsDeep param controls the level of recursion
 // when collecting file info in subdirectories. levelsDeep == 0 will only create an entry for inputPath.
 // Consider the number of files that may be under the root directory and the memory required to represent them
-// when choosing this value.
+// when choosing this value. Any EntryValidator given in filters is checked against inputPath.
+// inputPath may also be StdinPath or @file to read a newline or NUL separated list of paths, each of which becomes a child.
 func NewEntry(inputPath string, levelsDeep uint8, filters ...EntriesFilter) (Entry, error) {
 
+	if isPathList(inputPath) {
+		return newPathListEntry(inputPath, levelsDeep, filters...)
+	}
+
+	var validators = validatorsOf(filters)
+	if len(validators) > 0 {
+		var absolutePath, err = absolutePath(inputPath)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		for _, v := range validators {
+			if err := v.prepare(absolutePath); err != nil {
+				return Entry{}, err
+			}
+		}
+	}
+
 	var root, err = newEntry(inputPath)
 	if err != nil {
 		return Entry{}, err
 	}
 
+	for _, v := range validators {
+		if err := v.validate(root); err != nil {
+			return Entry{}, err
+		}
+	}
+
 	var currLevel = &root
 	if currLevel.IsDir() && levelsDeep > 0 && len(root.Children) == 0 {
 
@@ -38,11 +64,16 @@ func NewEntry(inputPath string, levelsDeep uint8, filters ...EntriesFilter) (Ent
 	return root, nil
 }
 
-// newEntry takes a filepath and expands ~ as well as other relative paths to absolute and stats them returning Entry.
+// newEntry takes a filepath or file:// URI and expands ~ as well as other relative paths to absolute and stats them returning Entry.
 func newEntry(inputPath string) (Entry, error) {
 
 	var entry = Entry{}
 	var err error
+
+	inputPath, err = fromFileURI(inputPath)
+	if err != nil {
+		return Entry{}, err
+	}
 	inputPath = filepath.Clean(strings.TrimSpace(inputPath))
 
 	inputPath, unglobbedFilenames, err := unglobInput(inputPath)
@@ -81,6 +112,26 @@ func newEntry(inputPath string) (Entry, error) {
 	return entry, nil
 }
 
+// absolutePath expands ~ and relative paths to absolute without unglobbing or stating them.
+func absolutePath(inputPath string) (string, error) {
+
+	inputPath, err := fromFileURI(inputPath)
+	if err != nil {
+		return "", err
+	}
+
+	inputPath, _, err = unglobInput(filepath.Clean(strings.TrimSpace(inputPath)))
+	if err != nil {
+		return "", fmt.Errorf("error unglobbing input: %s, error: %w", inputPath, err)
+	}
+
+	absolutePath, err := filepath.Abs(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("error getting absolute path, error: %w", err)
+	}
+	return absolutePath, nil
+}
+
 // populateChildren recursively populates the children of an Entry.
 func (e *Entry) populateChildren(levels uint8, filters ...EntriesFilter) error {
 	files, err := os.ReadDir(e.AbsolutePath)
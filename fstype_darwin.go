@@ -0,0 +1,34 @@
+//go:build darwin
+
+package path
+
+import "syscall"
+
+// remoteFileSystems are the names of file systems that do not report changes made elsewhere.
+var remoteFileSystems = map[string]struct{}{
+	"nfs":     {},
+	"smbfs":   {},
+	"afpfs":   {},
+	"webdav":  {},
+	"macfuse": {},
+	"osxfuse": {},
+}
+
+// isRemoteFileSystem reports if path is on a network or FUSE file system.
+func isRemoteFileSystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+
+	var name = make([]byte, 0, len(stat.Fstypename))
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+
+	var _, remote = remoteFileSystems[string(name)]
+	return remote
+}
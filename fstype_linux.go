@@ -0,0 +1,31 @@
+//go:build linux
+
+package path
+
+import "syscall"
+
+// remoteFileSystems are the statfs magic numbers of file systems that do not report changes made elsewhere.
+var remoteFileSystems = map[int64]struct{}{
+	0x6969:     {}, // NFS
+	0x517B:     {}, // SMB
+	0xFF534D42: {}, // CIFS
+	0xFE534D42: {}, // SMB2
+	0x65735546: {}, // FUSE
+	0x01021997: {}, // 9P
+	0x5346414F: {}, // AFS
+	0x73757245: {}, // CODA
+	0x47504653: {}, // GPFS
+	0x0BD00BD0: {}, // Lustre
+	0x00C36400: {}, // Ceph
+}
+
+// isRemoteFileSystem reports if path is on a network or FUSE file system.
+func isRemoteFileSystem(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+
+	var _, remote = remoteFileSystems[int64(stat.Type)] //nolint:unconvert // Type is int32 on some architectures
+	return remote
+}
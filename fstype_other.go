@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package path
+
+// isRemoteFileSystem can not tell file systems apart on this platform, WithPolling has to be used explicitly.
+func isRemoteFileSystem(string) bool {
+	return false
+}
@@ -0,0 +1,160 @@
+package path
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// isGlob reports if path is a glob pattern.
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, globMeta)
+}
+
+// splitGlob splits an absolute pattern into the deepest directory without glob meta characters and the path
+// segments below it, e.g. /data/*/incoming is /data and [* incoming].
+func splitGlob(pattern string) (string, []string) {
+	var base = filepath.Dir(pattern[:strings.IndexAny(pattern, globMeta)+1])
+	var rel, _ = filepath.Rel(base, pattern)
+	return base, strings.Split(rel, string(filepath.Separator))
+}
+
+// globScaffold returns the directories that have to be watched to see new matches of pattern appear: the base and
+// every directory matching a leading part of the pattern.
+func globScaffold(pattern string) []string {
+	var base, segments = splitGlob(pattern)
+	var dirs = []string{base}
+
+	for i := 1; i < len(segments); i++ {
+		var matches, _ = filepath.Glob(filepath.Join(append([]string{base}, segments[:i]...)...))
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				dirs = append(dirs, match)
+			}
+		}
+	}
+	return dirs
+}
+
+// existingAncestor returns the closest directory above path that exists.
+func existingAncestor(path string) string {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() || dir == filepath.Dir(dir) {
+			return dir
+		}
+	}
+}
+
+// scaffoldFor returns the directories that have to be watched to see path, a path or glob pattern given to Add,
+// appear. Paths only need them with WithMissingPaths, to be found again after being removed.
+func (dw *dirWatcher) scaffoldFor(path string) []string {
+	if isGlob(path) {
+		return globScaffold(path)
+	} else if dw.config.missingPaths {
+		return []string{existingAncestor(path)}
+	}
+	return nil
+}
+
+// matches returns what path, a path or glob pattern given to Add, refers to right now.
+func matches(path string) []string {
+	if isGlob(path) {
+		var matches, _ = filepath.Glob(path) // the pattern was checked by Add
+		return matches
+	} else if _, err := os.Lstat(path); err == nil {
+		return []string{path}
+	}
+	return nil
+}
+
+// expandPaths watches the scaffold of every glob pattern, and every path with WithMissingPaths, and then every match
+// that is not watched yet. With announce those matches are published as created, along with everything in them,
+// otherwise that only happens with WithInitialEvents.
+func (dw *dirWatcher) expandPaths(announce bool) {
+	for path := range dw.paths {
+		var scaffold = dw.scaffoldFor(path)
+		if scaffold == nil {
+			continue
+		}
+
+		for _, dir := range scaffold {
+			if _, has := dw.scaffold[dir]; has {
+				continue
+			}
+			if err := dw.watcher.Add(dir); err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					dw.sendError(err)
+				}
+				continue
+			}
+			dw.scaffold[dir] = struct{}{}
+		}
+
+		for _, match := range matches(path) {
+			if dw.dirs.has(match) {
+				continue
+			}
+
+			var found, err = dw.addRoot(match)
+			if err != nil {
+				dw.sendError(err)
+				continue
+			}
+			if announce {
+				if info, err := os.Lstat(match); err == nil && info.IsDir() {
+					found = append([]Entry{{AbsolutePath: match, FileInfo: info}}, found...)
+				}
+				dw.publishListed(found)
+			} else {
+				dw.announce(match, found)
+			}
+		}
+	}
+}
+
+// pruneScaffold stops watching scaffold directories no path needs anymore and re-adds the rest, their watch may have
+// been dropped along with a root.
+func (dw *dirWatcher) pruneScaffold() {
+	var needed = make(map[string]struct{})
+	for path := range dw.paths {
+		for _, dir := range dw.scaffoldFor(path) {
+			needed[dir] = struct{}{}
+		}
+	}
+
+	for dir := range dw.scaffold {
+		if _, has := needed[dir]; has {
+			_ = dw.watcher.Add(dir)
+			continue
+		}
+		if !dw.dirs.has(dir) {
+			unwatch(dw.watcher, dir)
+		}
+		delete(dw.scaffold, dir)
+	}
+}
+
+// followScaffold keeps the scaffold in step with event and looks for new matches when something is created in it.
+// With WithMissingPaths a removed scaffold directory is replaced by the closest one above it that still exists.
+func (dw *dirWatcher) followScaffold(event fsnotify.Event) {
+	if len(dw.scaffold) == 0 {
+		return
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		if _, has := dw.scaffold[event.Name]; has {
+			delete(dw.scaffold, event.Name)
+			if dw.config.missingPaths {
+				dw.expandPaths(true)
+			}
+		}
+		return
+	}
+	if _, has := dw.scaffold[filepath.Dir(event.Name)]; has && event.Has(fsnotify.Create) {
+		dw.expandPaths(true)
+	}
+}
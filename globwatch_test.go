@@ -0,0 +1,141 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitGlob(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+
+	base, segments := splitGlob(filepath.Join(root, "*", "incoming"))
+	assert.Equal(t, root, base)
+	assert.Equal(t, []string{"*", "incoming"}, segments)
+
+	base, segments = splitGlob(filepath.Join(root, "data", "in*"))
+	assert.Equal(t, filepath.Join(root, "data"), base)
+	assert.Equal(t, []string{"in*"}, segments)
+
+	assert.True(t, isGlob(filepath.Join(root, "file[0-9]")))
+	assert.False(t, isGlob(root))
+}
+
+func TestGlobScaffold(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "a", "x", "incoming"), os.ModePerm))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "b", "y"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "file.txt"), []byte{}, os.ModePerm))
+
+	var scaffold = globScaffold(filepath.Join(root, "*", "*", "incoming"))
+	assert.Equal(t, []string{
+		root,
+		filepath.Join(root, "a"),
+		filepath.Join(root, "b"),
+		filepath.Join(root, "a", "x"),
+		filepath.Join(root, "b", "y"),
+	}, scaffold)
+}
+
+func TestWatcherGlob(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "a", "incoming"), os.ModePerm))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "a", "outgoing"), os.ModePerm))
+
+	var watcher, err = NewWatcher(t.Context(), 0, NewOpWatchFilter(fsnotify.Create))
+	assert.NoError(t, err)
+	defer watcher.Close()
+
+	var pattern = filepath.Join(root, "*", "incoming")
+	assert.ErrorIs(t, watcher.Add(filepath.Join(root, "missing", "*")), os.ErrNotExist)
+	assert.Error(t, watcher.Add(filepath.Join(root, "[")))
+	assert.NoError(t, watcher.Add(pattern))
+	assert.Equal(t, []string{pattern}, watcher.WatchedPaths())
+
+	// existing matches are watched, and nothing else is reported
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "outgoing", "skipped.txt"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "incoming", "one.txt"), []byte{}, os.ModePerm))
+	var event = <-watcher.Events()
+	assert.Equal(t, filepath.Join(root, "a", "incoming", "one.txt"), event.AbsolutePath)
+
+	// a new match is picked up along with anything already in it
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "b", "incoming"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "b", "incoming", "two.txt"), []byte{}, os.ModePerm))
+
+	var seen = make(map[string]int)
+	var timeout = time.After(time.Second * 5)
+	for len(seen) < 2 {
+		select {
+		case event := <-watcher.Events():
+			seen[event.AbsolutePath]++
+		case <-timeout:
+			assert.FailNow(t, "missing events", "%v", seen)
+		}
+	}
+	assert.Equal(t, map[string]int{filepath.Join(root, "b", "incoming"): 1, filepath.Join(root, "b", "incoming", "two.txt"): 1}, seen)
+
+	// removing the pattern drops every match
+	assert.NoError(t, watcher.Remove(pattern))
+	assert.Empty(t, watcher.WatchedPaths())
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "incoming", "three.txt"), []byte{}, os.ModePerm))
+
+	select {
+	case event := <-watcher.Events():
+		assert.Fail(t, "event after the pattern was removed", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 250):
+	}
+}
+
+func TestWatcherMissingPaths(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	var target = filepath.Join(root, "a", "b", "out")
+
+	var watcher, err = NewWatcher(t.Context(), 1, WithMissingPaths())
+	assert.NoError(t, err)
+	defer watcher.Close()
+	assert.NoError(t, watcher.Add(target))
+	assert.Equal(t, []string{target}, watcher.WatchedPaths())
+
+	// waitFor reads events until op is seen for path, and reports everything else that was seen on the way
+	var waitFor = func(path string, op fsnotify.Op) []string {
+		var seen []string
+		var timeout = time.After(time.Second * 5)
+		for {
+			select {
+			case event := <-watcher.Events():
+				if event.AbsolutePath == path && event.Has(op) {
+					return seen
+				}
+				seen = append(seen, event.AbsolutePath)
+			case <-timeout:
+				assert.FailNow(t, "missing event", "%s %s", op, path)
+			}
+		}
+	}
+
+	// the path is watched once it is created, its parents are not reported
+	assert.NoError(t, os.MkdirAll(target, os.ModePerm))
+	assert.Empty(t, waitFor(target, fsnotify.Create))
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "one.txt"), []byte{}, os.ModePerm))
+	assert.Empty(t, waitFor(filepath.Join(target, "one.txt"), fsnotify.Create))
+
+	// and again after it was removed along with its parents
+	assert.NoError(t, os.RemoveAll(filepath.Join(root, "a")))
+	waitFor(target, fsnotify.Remove)
+	assert.NoError(t, os.MkdirAll(target, os.ModePerm))
+	waitFor(target, fsnotify.Create)
+	assert.NoError(t, os.WriteFile(filepath.Join(target, "two.txt"), []byte{}, os.ModePerm))
+	assert.Empty(t, waitFor(filepath.Join(target, "two.txt"), fsnotify.Create))
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package path
+
+import (
+	"io/fs"
+)
+
+// inode returns the inode number of info, false if it is not known. Inodes are only available on unix.
+func inode(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
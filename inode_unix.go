@@ -0,0 +1,19 @@
+//go:build unix
+
+package path
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inode returns the inode number of info, false if it is not known.
+func inode(info fs.FileInfo) (uint64, bool) {
+	if info == nil {
+		return 0, false
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino), true //nolint: unconvert // Ino is not a uint64 on every platform
+	}
+	return 0, false
+}
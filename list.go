@@ -4,9 +4,14 @@ import (
 	"os"
 )
 
-// List is just a convience function to get a slice of files.
+// List is just a convience function to get a slice of files. includeRoot is ignored when inputPath is a list of paths
+// (StdinPath or @file) as the list itself is not part of the results.
 func List(inputPath string, levelsDeep uint8, includeRoot bool, filters ...EntriesFilter) ([]Entry, error) {
 
+	if isPathList(inputPath) {
+		includeRoot = false
+	}
+
 	var entry, err = NewEntry(inputPath, levelsDeep, filters...)
 	if err != nil {
 		return nil, err
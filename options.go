@@ -0,0 +1,247 @@
+package path
+
+import (
+	"math"
+	"runtime"
+	"time"
+)
+
+// WatchOption configures WatchDirOptions, NewWatcher and the other watches. Every WatchFilter is also a WatchOption so
+// filters and options can be passed together.
+type WatchOption interface {
+	apply(c *watchConfig)
+}
+
+// watchConfig is the result of applying every WatchOption.
+type watchConfig struct {
+	filters       []WatchFilter
+	debounce      time.Duration
+	renameWindow  time.Duration
+	clock         Clock
+	pollInterval  time.Duration
+	pollFallback  bool
+	initialEvents bool
+	missingPaths  bool
+	chmodEvents   bool
+	watchDepth    int // -1 to follow recursiveDepth
+	prune         []string
+	ignoreFile    string
+	writeStable   time.Duration
+	stateFile     string
+	stateInterval time.Duration
+	workers       int
+	shardQueue    int
+	retries       int
+	retryBackoff  time.Duration
+	errorHandler  func(error)
+	shardKey      ShardKey
+}
+
+func newWatchConfig(opts ...WatchOption) watchConfig {
+	var c = watchConfig{clock: realClock{}, watchDepth: -1, workers: runtime.GOMAXPROCS(0), shardQueue: defaultShardQueue, shardKey: ShardByPath}
+	for _, opt := range opts {
+		opt.apply(&c)
+	}
+	return c
+}
+
+// depth returns how many levels below each root directories are watched, see WithWatchDepth.
+func (c watchConfig) depth(recursiveDepth uint8) uint8 {
+	switch {
+	case c.watchDepth >= 0:
+		return uint8(c.watchDepth)
+	case recursiveDepth > 0:
+		return math.MaxUint8
+	}
+	return 0
+}
+
+// watchOptionFunc adapts a func to a WatchOption.
+type watchOptionFunc func(c *watchConfig)
+
+func (f watchOptionFunc) apply(c *watchConfig) {
+	f(c)
+}
+
+// WithFilters adds filters to a watch, it is only needed when the filters are already in a []WatchFilter.
+func WithFilters(filters ...WatchFilter) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.filters = append(c.filters, filters...)
+	})
+}
+
+// WithDebounce holds events until their path has been quiet for window and then publishes a single WatchEvent whose
+// Op is every fsnotify.Op seen in that time, e.g. saving a file becomes one Create|Write|Chmod event.
+// A window <= 0 publishes every event as it happens, which is the default.
+func WithDebounce(window time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.debounce = window
+	})
+}
+
+// WithRenameTracking pairs the fsnotify.Rename of a moved path's old name with the fsnotify.Create of its new name
+// and publishes them as one fsnotify.Rename WatchEvent with OldPath and NewPath set. The pair is matched on inode
+// number where the platform has them. A rename that is not paired within window was moved out of the watch and is
+// published with an empty NewPath. Moves into the watch can not be told apart from a create and are published as one.
+func WithRenameTracking(window time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.renameWindow = window
+	})
+}
+
+// WithPolling watches by listing every watched directory each interval instead of using the OS notification APIs.
+// It is slower and coarser, changes within one interval are merged, but works on any file system.
+func WithPolling(interval time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.pollInterval = interval
+		c.pollFallback = false
+	})
+}
+
+// WithPollingFallback polls every interval, as with WithPolling, only when the root is on a network or FUSE file
+// system such as NFS, SMB or sshfs, which do not report changes made by other machines, or when the OS notification
+// APIs are not available.
+func WithPollingFallback(interval time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.pollInterval = interval
+		c.pollFallback = true
+	})
+}
+
+// WithInitialEvents publishes a fsnotify.Create WatchEvent for everything that is already in a path when it starts
+// being watched, before any live events for it. Filters apply as usual. Paths created while the watch is starting
+// are published exactly once.
+func WithInitialEvents() WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.initialEvents = true
+	})
+}
+
+// WithWatchDepth only watches directories up to depth levels below each path given to Add, events deeper than that
+// are not published. By default a watch with a recursiveDepth > 0 watches the whole tree below its paths and one with
+// a recursiveDepth of 0 only the paths themselves; the recursiveDepth does not limit what is watched.
+func WithWatchDepth(depth uint8) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.watchDepth = int(depth)
+	})
+}
+
+// WithPrune leaves out every path matching one of patterns, directories are not watched at all, e.g. node_modules
+// or .git which would otherwise use up the OS watch limit, see ErrWatchLimit. Patterns are filepath.Match patterns
+// with slashes as separators. Patterns without a slash match the name of a path at any depth, others match the path
+// relative to its root, and a trailing slash only matches directories, e.g. "*.log", "build/" or "web/dist".
+func WithPrune(patterns ...string) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.prune = append(c.prune, patterns...)
+	})
+}
+
+// WithIgnoreFile reads more WithPrune patterns from the file called name in each path given to Add, when it has one,
+// e.g. ".gitignore". Lines that are empty or start with # are skipped. Negated patterns, starting with !, are not
+// supported and skipped too. The file is read once, when the path is added.
+func WithIgnoreFile(name string) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.ignoreFile = name
+	})
+}
+
+// WithChmodEvents publishes events that are only a fsnotify.Chmod. They are dropped by default, editors, backup
+// tools and indexers touch permissions and timestamps all the time and few watches care. An OpWatchFilter that asks
+// for fsnotify.Chmod implies WithChmodEvents.
+func WithChmodEvents() WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.chmodEvents = true
+	})
+}
+
+// WithMissingPaths lets Add take paths that do not exist yet, such as a mount point or an output directory, they are
+// watched as soon as they are created. A watched path that is removed is watched again once it is back. Until then
+// the closest directory above it that exists is watched, and its creation is published like that of anything else.
+func WithMissingPaths() WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.missingPaths = true
+	})
+}
+
+// WithWriteFinished holds the events of a file that is created or written to until its size and modification time
+// have not changed for stable, and then publishes a single WatchEvent with Ready and every Op seen in that time,
+// e.g. an upload becomes one Create|Write|Ready event once it is complete. A file removed or renamed before then
+// is not published as ready. Directories are not held.
+func WithWriteFinished(stable time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.writeStable = stable
+	})
+}
+
+// WithStateFile keeps the metadata of everything under watch in file, it is saved every interval and when the watch
+// stops. When a path that was in the saved state is added again, what changed while nobody was watching is published
+// first: creates, writes and removes. An interval <= 0 only saves when the watch stops.
+func WithStateFile(file string, interval time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.stateFile = file
+		c.stateInterval = interval
+	})
+}
+
+// WithWorkers sets how many handlers a Dispatcher, and so WatchFunc, runs at the same time, the default is
+// runtime.GOMAXPROCS(0). Values < 1 are ignored.
+func WithWorkers(n int) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		if n > 0 {
+			c.workers = n
+		}
+	})
+}
+
+// WithShardQueue sets how many events a Dispatcher queues for a shard behind the one being handled, the default is
+// 1024. Dispatch blocks while the queue of its shard is full. Values < 1 are ignored.
+func WithShardQueue(n int) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		if n > 0 {
+			c.shardQueue = n
+		}
+	})
+}
+
+// WithShardKey sets how a Dispatcher shards events, the default is ShardByPath.
+func WithShardKey(key ShardKey) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.shardKey = key
+	})
+}
+
+// WithRetry calls a Dispatcher or WatchFunc handler that returned an error again, up to retries times, first after
+// backoff and then after twice as long as the time before. The default is to not retry.
+func WithRetry(retries int, backoff time.Duration) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.retries = retries
+		c.retryBackoff = backoff
+	})
+}
+
+// WithErrorHandler gives the errors of the watch itself to fn, WatchFunc drops them otherwise. fn is called from
+// the goroutine that reads the events, so a slow fn holds them up.
+func WithErrorHandler(fn func(error)) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.errorHandler = fn
+	})
+}
+
+// WithClock replaces the clock used by the timing based options such as WithDebounce, it is meant for tests.
+func WithClock(clock Clock) WatchOption {
+	return watchOptionFunc(func(c *watchConfig) {
+		c.clock = clock
+	})
+}
+
+// Clock is the source of time for a watch.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,103 @@
+package path
+
+import (
+	"math"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock that only moves when Advance is called.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2022, 06, 01, 0, 0, 0, 0, time.UTC)}
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	return fc.now
+}
+
+func (fc *fakeClock) After(d time.Duration) <-chan time.Time {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	var ch = make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- fc.now
+		return ch
+	}
+	fc.waiters = append(fc.waiters, fakeWaiter{deadline: fc.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires every waiter that is now due.
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.now = fc.now.Add(d)
+	var waiting = fc.waiters[:0]
+	for _, w := range fc.waiters {
+		if w.deadline.After(fc.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.ch <- fc.now
+	}
+	fc.waiters = waiting
+}
+
+func TestNewWatchConfig(t *testing.T) {
+	t.Parallel()
+
+	var config = newWatchConfig()
+	assert.Empty(t, config.filters)
+	assert.Equal(t, time.Duration(0), config.debounce)
+	assert.IsType(t, realClock{}, config.clock)
+
+	var clock = newFakeClock()
+	var regexFilter = NewRegexWatchFilter(regexp.MustCompile(".*.txt$"))
+	var filters = []WatchFilter{NewOpWatchFilter(fsnotify.Create), NewSizeWatchFilter(0, 10)}
+
+	config = newWatchConfig(regexFilter, WithFilters(filters...), WithDebounce(time.Second), WithClock(clock))
+	assert.Len(t, config.filters, 3)
+	assert.Equal(t, regexFilter, config.filters[0])
+	assert.Equal(t, time.Second, config.debounce)
+	assert.Equal(t, clock, config.clock)
+
+	assert.Equal(t, uint8(0), newWatchConfig().depth(0))
+	assert.Equal(t, uint8(math.MaxUint8), newWatchConfig().depth(1))
+	assert.Equal(t, uint8(3), newWatchConfig(WithWatchDepth(3)).depth(1))
+	assert.Equal(t, uint8(2), newWatchConfig(WithWatchDepth(2)).depth(0))
+}
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+
+	var clock = newFakeClock()
+	var start = clock.Now()
+	var after = clock.After(time.Second)
+
+	clock.Advance(time.Millisecond * 999)
+	assert.Empty(t, after)
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-after)
+	assert.Empty(t, clock.waiters)
+}
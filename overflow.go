@@ -0,0 +1,88 @@
+package path
+
+import (
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchStats counts what a Watcher had to recover from.
+type WatchStats struct {
+	// Overflows is how often the OS event queue overflowed and events were dropped.
+	Overflows uint64
+	// Recovered is how many events were published from the rescans that followed.
+	Recovered uint64
+}
+
+// watchCounters is the live form of WatchStats.
+type watchCounters struct {
+	overflows atomic.Uint64
+	recovered atomic.Uint64
+}
+
+// rescan walks every root after the OS dropped events and publishes how the tree differs from the metadata cache.
+// Directories created meanwhile are watched as well. Anything that changed more than once is published once.
+func (dw *dirWatcher) rescan() {
+	dw.counters.overflows.Add(1)
+
+	// directory roots stay watched and are not in what addRoot finds, they must not look removed
+	var known = make(map[string]stateEntry)
+	for p, info := range dw.cache.snapshot() {
+		if info.IsDir() && dw.dirs.isRoot(p) {
+			continue
+		}
+		known[p] = newStateEntry(info)
+	}
+
+	var found []Entry
+	for _, root := range dw.dirs.rootList() {
+		var entries, err = dw.addRoot(root)
+		if err != nil {
+			dw.sendError(err)
+			continue
+		}
+		found = append(found, entries...)
+	}
+
+	dw.counters.recovered.Add(uint64(dw.reconcile(known, found, true)))
+
+	// and glob patterns may have new matches, missing paths may exist now
+	dw.expandPaths(true)
+}
+
+// reconcile publishes the difference between before and found, the paths that existed then and the ones that exist
+// now. Without all only the removals are published. It returns how many events were published.
+func (dw *dirWatcher) reconcile(before map[string]stateEntry, found []Entry, all bool) int {
+	var published int
+	var created []Entry
+	for _, entry := range found {
+		var was, existed = before[entry.AbsolutePath]
+		delete(before, entry.AbsolutePath)
+
+		switch {
+		case !all:
+		case !existed:
+			created = append(created, entry)
+		case was.changed(newStateEntry(entry.FileInfo)) && !entry.IsDir():
+			dw.queue(trackedEvent{Event: fsnotify.Event{Name: entry.AbsolutePath, Op: fsnotify.Write}})
+			published++
+		}
+	}
+	dw.publishListed(created)
+	published += len(created)
+
+	var removed = make([]string, 0, len(before))
+	for p := range before {
+		removed = append(removed, p)
+	}
+	slices.Sort(removed)
+	for _, p := range removed {
+		var info = fileInfo{name: filepath.Base(p), size: before[p].Size, mode: before[p].Mode, modTime: before[p].ModTime}
+		dw.cache.remove(p)
+		dw.dirs.remove(p)
+		dw.queue(trackedEvent{Event: fsnotify.Event{Name: p, Op: fsnotify.Remove}, info: info})
+	}
+	return published + len(removed)
+}
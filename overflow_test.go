@@ -0,0 +1,130 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// overflowBackend is a watchBackend that never reports events, only the errors the test sends.
+type overflowBackend struct {
+	watchBackend
+	errors chan error
+}
+
+func (ob overflowBackend) Errors() <-chan error {
+	return ob.errors
+}
+
+func TestWatcherOverflow(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("a"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "removed.txt"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "same.txt"), []byte{}, os.ModePerm))
+
+	// a poller that is never advanced misses everything
+	var backend = overflowBackend{watchBackend: newPollBackend(time.Second, newFakeClock()), errors: make(chan error)}
+	defer backend.Close()
+
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var done = make(chan struct{})
+	var dw = newDirWatcher(backend, 1, files, errs, done, newWatchConfig())
+	go dw.run()
+	defer close(done)
+
+	assert.NoError(t, dw.addPath(dir))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("aaa"), os.ModePerm))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "removed.txt")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "created.txt"), []byte{}, os.ModePerm))
+
+	backend.errors <- fsnotify.ErrEventOverflow
+
+	var seen = make(map[string]fsnotify.Op)
+	for len(seen) < 4 {
+		var event = <-files
+		seen[event.AbsolutePath] = event.Op
+	}
+	assert.Equal(t, map[string]fsnotify.Op{
+		filepath.Join(dir, "changed.txt"):        fsnotify.Write,
+		filepath.Join(dir, "removed.txt"):        fsnotify.Remove,
+		filepath.Join(dir, "sub"):                fsnotify.Create,
+		filepath.Join(dir, "sub", "created.txt"): fsnotify.Create,
+	}, seen)
+
+	// other errors are passed on
+	backend.errors <- os.ErrPermission
+	assert.ErrorIs(t, <-errs, os.ErrPermission)
+
+	// the new directory is watched from now on
+	assert.True(t, dw.dirs.has(filepath.Join(dir, "sub")))
+	assert.Equal(t, uint64(1), dw.counters.overflows.Load())
+	assert.Equal(t, uint64(4), dw.counters.recovered.Load())
+}
+
+func TestWatcherOverflowRoot(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes can not be changed")
+	}
+
+	var dir = t.TempDir()
+	var watcher, err = fsnotify.NewWatcher()
+	assert.NoError(t, err)
+	var backend = overflowBackend{watchBackend: fsnotifyBackend{Watcher: watcher}, errors: make(chan error)}
+	defer backend.Close()
+
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var done = make(chan struct{})
+	var dw = newDirWatcher(backend, 1, files, errs, done, newWatchConfig())
+	go dw.run()
+	defer close(done)
+
+	assert.NoError(t, dw.addPath(dir))
+
+	// the chmod puts the root in the metadata cache
+	assert.NoError(t, os.Chmod(dir, 0o700))
+	time.Sleep(time.Millisecond * 100)
+	backend.errors <- fsnotify.ErrEventOverflow
+
+	// the root is still watched and not reported removed
+	var file = filepath.Join(dir, "after.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, os.ModePerm))
+	for {
+		select {
+		case event := <-files:
+			assert.NotEqual(t, fsnotify.Remove, event.Op, event.AbsolutePath)
+			if event.AbsolutePath != file {
+				continue
+			}
+		case <-time.After(time.Second * 5):
+			assert.FailNow(t, "no event after the rescan")
+		}
+		break
+	}
+	assert.True(t, dw.dirs.has(dir))
+}
+
+func TestWatcherStats(t *testing.T) {
+	t.Parallel()
+
+	var watcher, err = NewWatcher(t.Context(), 0)
+	assert.NoError(t, err)
+	defer watcher.Close()
+
+	assert.Equal(t, WatchStats{}, watcher.Stats())
+	watcher.dw.counters.overflows.Add(2)
+	watcher.dw.counters.recovered.Add(10)
+	assert.Equal(t, WatchStats{Overflows: 2, Recovered: 10}, watcher.Stats())
+}
@@ -0,0 +1,119 @@
+package path
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StdinPath is the input path that reads a list of paths from stdin.
+const StdinPath = "-"
+
+// pathListPrefix marks an input path as a file containing a list of paths, e.g. @paths.txt.
+const pathListPrefix = "@"
+
+// stdin is where StdinPath reads from, it is a var so tests can replace it.
+var stdin io.Reader = os.Stdin
+
+// isPathList reports if inputPath refers to a list of paths rather than a path. A file whose name actually starts with @
+// can still be used by prefixing it with ./
+func isPathList(inputPath string) bool {
+	inputPath = strings.TrimSpace(inputPath)
+	return inputPath == StdinPath || (strings.HasPrefix(inputPath, pathListPrefix) && len(inputPath) > len(pathListPrefix))
+}
+
+// newPathListEntry reads the list of paths that inputPath refers to and creates an Entry whose children are the listed paths.
+// The root Entry describes where the list was read from. The lines are literal paths, lists are not nested.
+func newPathListEntry(inputPath string, levelsDeep uint8, filters ...EntriesFilter) (Entry, error) {
+
+	var root Entry
+	var reader io.Reader
+
+	inputPath = strings.TrimSpace(inputPath)
+	if inputPath == StdinPath {
+		root.AbsolutePath = StdinPath
+		if f, ok := stdin.(*os.File); ok {
+			var info, err = f.Stat()
+			if err != nil {
+				return Entry{}, fmt.Errorf("error stating stdin, error: %w", err)
+			}
+			root.FileInfo = info
+		} else {
+			root.FileInfo = fileInfo{name: StdinPath, mode: fs.ModeNamedPipe}
+		}
+		reader = stdin
+	} else {
+		var listFile, err = newEntry(strings.TrimPrefix(inputPath, pathListPrefix))
+		if err != nil {
+			return Entry{}, fmt.Errorf("error opening path list: %s, error: %w", inputPath, err)
+		}
+		root.AbsolutePath = listFile.AbsolutePath
+		root.FileInfo = listFile.FileInfo
+
+		f, err := os.Open(listFile.AbsolutePath)
+		if err != nil {
+			return Entry{}, fmt.Errorf("error opening path list: %s, error: %w", listFile.AbsolutePath, err)
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	paths, err := readPathList(reader)
+	if err != nil {
+		return Entry{}, fmt.Errorf("error reading path list: %s, error: %w", root.AbsolutePath, err)
+	}
+
+ListLoop:
+	for _, p := range paths {
+		// lines are paths, a line such as - or @paths.txt is not read as another list
+		if isPathList(p) {
+			p = "." + string(filepath.Separator) + strings.TrimSpace(p)
+		}
+
+		var entry, err = NewEntry(p, levelsDeep, filters...)
+		if err != nil {
+			return Entry{}, fmt.Errorf("error creating entry from path list: %s, line: %s, error: %w", root.AbsolutePath, p, err)
+		}
+
+		// same as populateChildren, dirs and symlinks are kept so their children can be filtered
+		if !entry.IsDir() && entry.FileInfo.Mode()&os.ModeSymlink != fs.ModeSymlink {
+			for _, fn := range filters {
+				if !fn.filter(entry) {
+					continue ListLoop
+				}
+			}
+		}
+
+		root.Children = append(root.Children, entry)
+	}
+
+	return root, nil
+}
+
+// readPathList splits r into paths. The list is NUL separated (e.g. find -print0) if it contains a NUL, otherwise newline separated.
+// Empty lines are skipped.
+func readPathList(r io.Reader) ([]string, error) {
+	var data, err = io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var sep = []byte{'\n'}
+	if bytes.IndexByte(data, 0) >= 0 {
+		sep = []byte{0}
+	}
+
+	var paths []string
+	for _, line := range bytes.Split(data, sep) {
+		var p = strings.TrimSuffix(string(line), "\r")
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
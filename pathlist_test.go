@@ -0,0 +1,90 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPathList(t *testing.T) {
+	t.Parallel()
+
+	var paths, err = readPathList(strings.NewReader("a\nb c\r\n\n  \nd\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b c", "d"}, paths)
+
+	// NUL separated lists may contain newlines in names
+	paths, err = readPathList(strings.NewReader("a\nb\x00c\x00\x00"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a\nb", "c"}, paths)
+}
+
+func TestIsPathList(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isPathList("-"))
+	assert.True(t, isPathList(" @paths.txt"))
+	assert.False(t, isPathList("@"))
+	assert.False(t, isPathList("./@paths.txt"))
+	assert.False(t, isPathList("./testdata"))
+}
+
+func TestPathListFile(t *testing.T) {
+	t.Parallel()
+
+	var listFile = filepath.Join(t.TempDir(), "paths.txt")
+	var list = "./testdata/one/file.txt\n./testdata/one/*.mp*\n./testdata/one\n"
+	assert.NoError(t, os.WriteFile(listFile, []byte(list), 0o600))
+
+	var entry, err = NewEntry("@"+listFile, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, listFile, entry.AbsolutePath)
+	assert.Len(t, entry.Children, 3)
+	assert.True(t, strings.HasSuffix(entry.Children[0].AbsolutePath, "file.txt"))
+	assert.Len(t, entry.Children[1].Children, 2)
+	assert.Len(t, entry.Children[2].Children, 4)
+
+	// file.txt, the glob and its two matches, one and its four children
+	files, err := List("@"+listFile, 1, true)
+	assert.NoError(t, err)
+	assert.Len(t, files, 9)
+	assert.False(t, Contains(files, listFile))
+
+	files, err = List("@"+listFile, 1, false, NewFileEntitiesFilter())
+	assert.NoError(t, err)
+	assert.Len(t, files, 7)
+
+	var flagEntry Entry
+	assert.NoError(t, flagEntry.Set("@"+listFile))
+	assert.Len(t, flagEntry.Children, 3)
+
+	_, err = NewEntry("@"+filepath.Join(t.TempDir(), "notexist"), 0)
+	assert.Contains(t, err.Error(), "error opening path list")
+
+	// a list that names itself, or stdin, is not expanded again
+	assert.NoError(t, os.WriteFile(listFile, []byte("@"+listFile+"\n"), 0o600))
+	_, err = NewEntry("@"+listFile, 0)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoError(t, os.WriteFile(listFile, []byte("-\n"), 0o600))
+	_, err = NewEntry("@"+listFile, 0)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NoError(t, os.WriteFile(listFile, []byte("./testdata/notexist\n"), 0o600))
+	_, err = NewEntry("@"+listFile, 0)
+	assert.Contains(t, err.Error(), "line: ./testdata/notexist")
+}
+
+// nolint: paralleltest // replaces the package level stdin
+func TestPathListStdin(t *testing.T) {
+	stdin = strings.NewReader("./testdata/one/file.mp3\x00./testdata/one/file.mp4\x00")
+	defer func() { stdin = os.Stdin }()
+
+	var entry, err = NewEntry(StdinPath, 0, MustBeFile())
+	assert.NoError(t, err)
+	assert.Equal(t, StdinPath, entry.AbsolutePath)
+	assert.Len(t, entry.Children, 2)
+	assert.True(t, strings.HasSuffix(entry.Children[1].AbsolutePath, "file.mp4"))
+}
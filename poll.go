@@ -0,0 +1,250 @@
+package path
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// pollBackend is a watchBackend for file systems that do not deliver notifications such as NFS, SMB and FUSE.
+// Every interval each watched path is listed and compared with the previous listing, the differences are reported as
+// the fsnotify events the OS would have sent.
+type pollBackend struct {
+	interval  time.Duration
+	clock     Clock
+	mu        sync.Mutex
+	snapshots map[string]map[string]fs.FileInfo // watched path -> the path and its children
+	events    chan fsnotify.Event
+	errors    chan error
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+func newPollBackend(interval time.Duration, clock Clock) *pollBackend {
+	var pb = &pollBackend{
+		interval:  interval,
+		clock:     clock,
+		snapshots: make(map[string]map[string]fs.FileInfo),
+		events:    make(chan fsnotify.Event),
+		errors:    make(chan error),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go pb.run()
+	return pb
+}
+
+func (pb *pollBackend) Events() <-chan fsnotify.Event {
+	return pb.events
+}
+
+func (pb *pollBackend) Errors() <-chan error {
+	return pb.errors
+}
+
+// Add takes the first snapshot of path, changes are reported from the next poll on.
+func (pb *pollBackend) Add(path string) error {
+	var snapshot, err = takeSnapshot(path)
+	if err != nil {
+		return err
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	select {
+	case <-pb.done:
+		return fsnotify.ErrClosed
+	default:
+	}
+	pb.snapshots[path] = snapshot
+	return nil
+}
+
+func (pb *pollBackend) Remove(path string) error {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	if _, has := pb.snapshots[path]; !has {
+		return fmt.Errorf("%w: %s", fsnotify.ErrNonExistentWatch, path)
+	}
+	delete(pb.snapshots, path)
+	return nil
+}
+
+func (pb *pollBackend) WatchList() []string {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	var list = make([]string, 0, len(pb.snapshots))
+	for p := range pb.snapshots {
+		list = append(list, p)
+	}
+	return list
+}
+
+// watching reports if path has been added.
+func (pb *pollBackend) watching(path string) bool {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	var _, has = pb.snapshots[path]
+	return has
+}
+
+// Close stops polling and closes the event and error channels.
+func (pb *pollBackend) Close() error {
+	pb.closeOnce.Do(func() {
+		close(pb.done)
+		<-pb.stopped
+	})
+	return nil
+}
+
+func (pb *pollBackend) run() {
+	defer close(pb.stopped)
+	defer close(pb.errors)
+	defer close(pb.events)
+
+	for {
+		select {
+		case <-pb.done:
+			return
+		case <-pb.clock.After(pb.interval):
+			pb.poll()
+		}
+	}
+}
+
+// poll takes a new snapshot of every watched path and reports how it changed.
+func (pb *pollBackend) poll() {
+	var paths = pb.WatchList()
+	slices.Sort(paths)
+
+	for _, p := range paths {
+		var snapshot, err = takeSnapshot(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			// a watched directory inside another watch is reported by the diff of its parent
+			if pb.Remove(p) == nil && !pb.watching(filepath.Dir(p)) {
+				pb.send(fsnotify.Event{Name: p, Op: fsnotify.Remove})
+			}
+			continue
+		} else if err != nil {
+			pb.sendError(err)
+			continue
+		}
+
+		pb.mu.Lock()
+		var previous, has = pb.snapshots[p]
+		if has {
+			pb.snapshots[p] = snapshot
+		}
+		pb.mu.Unlock()
+
+		if !has { // removed while we were listing it
+			continue
+		}
+
+		for _, event := range diffSnapshots(p, previous, snapshot) {
+			if !pb.send(event) {
+				return
+			}
+		}
+	}
+}
+
+// send publishes event unless the backend is closed.
+func (pb *pollBackend) send(event fsnotify.Event) bool {
+	select {
+	case pb.events <- event:
+		return true
+	case <-pb.done:
+		return false
+	}
+}
+
+func (pb *pollBackend) sendError(err error) {
+	select {
+	case pb.errors <- err:
+	case <-pb.done:
+	}
+}
+
+// takeSnapshot lists path and its direct children. It only returns an error wrapping fs.ErrNotExist when path itself
+// is gone, children that are removed while it is listing are left out.
+func takeSnapshot(path string) (map[string]fs.FileInfo, error) {
+	var info, err = os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error stating watched path: %w", err)
+	}
+
+	var snapshot = map[string]fs.FileInfo{path: info}
+	if !info.IsDir() {
+		return snapshot, nil
+	}
+
+	children, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading watched dir: %w", err)
+	}
+
+	for _, child := range children {
+		var childPath = filepath.Join(path, child.Name())
+		var childInfo, err = os.Lstat(childPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // picked up as a remove, or not at all, on the next poll
+		} else if err != nil {
+			return nil, fmt.Errorf("error stating file: %w", err)
+		}
+		snapshot[childPath] = childInfo
+	}
+	return snapshot, nil
+}
+
+// diffSnapshots returns the events that turn previous into current, sorted by path. Changes to a watched
+// directory itself are left to the watch on its parent, as with inotify.
+func diffSnapshots(watched string, previous, current map[string]fs.FileInfo) []fsnotify.Event {
+	var events []fsnotify.Event
+
+	for p, info := range current {
+		var old, has = previous[p]
+		switch {
+		case !has:
+			events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Create})
+			continue
+		case p == watched && info.IsDir():
+			continue
+		}
+
+		if !info.IsDir() && (old.Size() != info.Size() || !old.ModTime().Equal(info.ModTime())) {
+			events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Write})
+		}
+		if old.Mode() != info.Mode() {
+			events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Chmod})
+		}
+	}
+
+	for p := range previous {
+		if _, has := current[p]; !has {
+			events = append(events, fsnotify.Event{Name: p, Op: fsnotify.Remove})
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b fsnotify.Event) int {
+		if a.Name < b.Name {
+			return -1
+		} else if a.Name > b.Name {
+			return 1
+		}
+		return 0
+	})
+	return events
+}
@@ -0,0 +1,226 @@
+package path
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// advancePoll waits for the poller to be waiting on clock and then lets it poll once.
+func advancePoll(clock *fakeClock, interval time.Duration) {
+	for {
+		clock.mu.Lock()
+		var waiting = len(clock.waiters)
+		clock.mu.Unlock()
+		if waiting > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(interval)
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "file.txt")
+	var gone = filepath.Join(dir, "gone.txt")
+	var sub = filepath.Join(dir, "sub")
+	assert.NoError(t, os.WriteFile(file, []byte("one"), 0o600))
+	assert.NoError(t, os.WriteFile(gone, []byte{}, 0o600))
+	assert.NoError(t, os.Mkdir(sub, os.ModePerm))
+
+	previous, err := takeSnapshot(dir)
+	assert.NoError(t, err)
+	assert.Len(t, previous, 4)
+
+	assert.NoError(t, os.WriteFile(file, []byte("three"), 0o600))
+	assert.NoError(t, os.Remove(gone))
+	assert.NoError(t, os.WriteFile(filepath.Join(sub, "deeper.txt"), []byte{}, 0o600)) // only sub's own watch sees this
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte{}, 0o600))
+
+	current, err := takeSnapshot(dir)
+	assert.NoError(t, err)
+
+	var expected = []fsnotify.Event{
+		{Name: file, Op: fsnotify.Write},
+		{Name: gone, Op: fsnotify.Remove},
+		{Name: filepath.Join(dir, "new.txt"), Op: fsnotify.Create},
+	}
+	assert.Equal(t, expected, diffSnapshots(dir, previous, current))
+
+	if runtime.GOOS != "windows" {
+		assert.NoError(t, os.Chmod(file, 0o644))
+		previous = current
+		current, err = takeSnapshot(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, []fsnotify.Event{{Name: file, Op: fsnotify.Chmod}}, diffSnapshots(dir, previous, current))
+	}
+}
+
+func TestPollBackend(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var sub = filepath.Join(dir, "sub")
+	assert.NoError(t, os.Mkdir(sub, os.ModePerm))
+
+	var clock = newFakeClock()
+	var pb = newPollBackend(time.Second, clock)
+	assert.NoError(t, pb.Add(dir))
+	assert.NoError(t, pb.Add(sub))
+	assert.ErrorIs(t, pb.Add(filepath.Join(dir, "missing")), os.ErrNotExist)
+	assert.ElementsMatch(t, []string{dir, sub}, pb.WatchList())
+
+	var file = filepath.Join(sub, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+	advancePoll(clock, time.Second)
+	assert.Equal(t, fsnotify.Event{Name: file, Op: fsnotify.Create}, <-pb.Events())
+
+	// a removed watch is reported once, by its parent
+	assert.NoError(t, os.RemoveAll(sub))
+	advancePoll(clock, time.Second)
+	assert.Equal(t, fsnotify.Event{Name: sub, Op: fsnotify.Remove}, <-pb.Events())
+	assert.Equal(t, []string{dir}, pb.WatchList())
+
+	assert.ErrorIs(t, pb.Remove(sub), fsnotify.ErrNonExistentWatch)
+	assert.NoError(t, pb.Remove(dir))
+	assert.Empty(t, pb.WatchList())
+
+	assert.NoError(t, pb.Close())
+	assert.NoError(t, pb.Close())
+	var _, open = <-pb.Events()
+	assert.False(t, open)
+	assert.ErrorIs(t, pb.Add(dir), fsnotify.ErrClosed)
+}
+
+func TestPollBackendChurn(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var clock = newFakeClock()
+	var pb = newPollBackend(time.Second, clock)
+	assert.NoError(t, pb.Add(dir))
+
+	// children that come and go while the directory is listed must not make it look removed
+	var stop = make(chan struct{})
+	var churned = make(chan struct{})
+	go func() {
+		defer close(churned)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			var file = filepath.Join(dir, strconv.Itoa(i%10))
+			_ = os.WriteFile(file, []byte{}, 0o600)
+			_ = os.Remove(file)
+		}
+	}()
+
+	var removed = make(chan bool)
+	go func() {
+		var dirRemoved bool
+		for event := range pb.Events() {
+			dirRemoved = dirRemoved || event.Name == dir
+		}
+		removed <- dirRemoved
+	}()
+	go func() {
+		for err := range pb.Errors() {
+			assert.NoError(t, err)
+		}
+	}()
+
+	for range 50 {
+		_, err := takeSnapshot(dir)
+		assert.NoError(t, err)
+		advancePoll(clock, time.Second)
+	}
+	close(stop)
+	<-churned
+
+	advancePoll(clock, time.Second) // wait for the last poll to finish
+	assert.Equal(t, []string{dir}, pb.WatchList())
+	assert.NoError(t, pb.Close())
+	assert.False(t, <-removed)
+}
+
+func TestWatchDirPolling(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 1, false, files, errs, WithPolling(time.Second), WithClock(clock))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	// directories created while polling are followed too
+	var sub = filepath.Join(dir, "sub")
+	assert.NoError(t, os.Mkdir(sub, os.ModePerm))
+	advancePoll(clock, time.Second)
+
+	var event = <-files
+	assert.Equal(t, fsnotify.Create, event.Op)
+	assert.Equal(t, sub, event.AbsolutePath)
+
+	var file = filepath.Join(sub, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("one"), 0o600))
+	advancePoll(clock, time.Second)
+
+	event = <-files
+	assert.Equal(t, fsnotify.Create, event.Op)
+	assert.Equal(t, file, event.AbsolutePath)
+
+	cancel()
+}
+
+func TestNewWatchBackend(t *testing.T) {
+	t.Parallel()
+
+	var backend, err = newWatchBackend(newWatchConfig(WithPolling(time.Second)))
+	assert.NoError(t, err)
+	assert.IsType(t, &pollBackend{}, backend)
+	assert.NoError(t, backend.Close())
+
+	backend, err = newWatchBackend(newWatchConfig())
+	assert.NoError(t, err)
+	assert.IsType(t, fsnotifyBackend{}, backend)
+	assert.NoError(t, backend.Close())
+
+	backend, err = newWatchBackend(newWatchConfig(WithPollingFallback(time.Second)))
+	assert.NoError(t, err)
+	assert.IsType(t, &fallbackBackend{}, backend)
+
+	// a local directory is not polled
+	var dir = t.TempDir()
+	if !isRemoteFileSystem(dir) {
+		assert.NoError(t, backend.Add(dir))
+		assert.Equal(t, []string{dir}, backend.(*fallbackBackend).notify.WatchList())
+		assert.Empty(t, backend.(*fallbackBackend).poll.WatchList())
+		assert.NoError(t, backend.Remove(dir))
+		assert.Empty(t, backend.WatchList())
+	}
+
+	assert.NoError(t, backend.Close())
+	var _, open = <-backend.Events()
+	assert.False(t, open)
+}
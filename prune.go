@@ -0,0 +1,135 @@
+package path
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pruneRule is one prune pattern. Patterns without a separator match the name of a path at any depth, others match
+// the path relative to its root. A trailing separator only matches directories.
+type pruneRule struct {
+	pattern  string
+	anchored bool
+	dirOnly  bool
+}
+
+func newPruneRule(pattern string) (pruneRule, error) {
+	var rule pruneRule
+	pattern = filepath.FromSlash(strings.TrimSpace(pattern))
+
+	if strings.HasSuffix(pattern, string(filepath.Separator)) {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, string(filepath.Separator))
+	}
+	if strings.HasPrefix(pattern, string(filepath.Separator)) {
+		rule.anchored = true
+		pattern = strings.TrimLeft(pattern, string(filepath.Separator))
+	}
+	if strings.ContainsRune(pattern, filepath.Separator) {
+		rule.anchored = true
+	}
+
+	if pattern == "" {
+		return rule, errors.New("empty prune pattern")
+	} else if _, err := filepath.Match(pattern, ""); err != nil {
+		return rule, fmt.Errorf("error with prune pattern: %s, error: %w", pattern, err)
+	}
+	rule.pattern = pattern
+	return rule, nil
+}
+
+// match reports if rel, a path relative to its root, matches the rule.
+func (pr pruneRule) match(rel string, isDir bool) bool {
+	if pr.dirOnly && !isDir {
+		return false
+	}
+
+	var name = rel
+	if !pr.anchored {
+		name = filepath.Base(rel)
+	}
+	var matched, _ = filepath.Match(pr.pattern, name) // checked by newPruneRule
+	return matched
+}
+
+// pruner holds the prune rules of a watch, those given to WithPrune and those read from the ignore file of each root.
+type pruner struct {
+	rules      []pruneRule
+	ignoreFile string
+	rootRules  map[string][]pruneRule
+}
+
+func newPruner(patterns []string, ignoreFile string) (*pruner, error) {
+	var p = &pruner{ignoreFile: ignoreFile, rootRules: make(map[string][]pruneRule)}
+	for _, pattern := range patterns {
+		var rule, err = newPruneRule(pattern)
+		if err != nil {
+			return nil, err
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p, nil
+}
+
+// load reads the ignore file of root, if there is one.
+func (p *pruner) load(root string) error {
+	if p.ignoreFile == "" {
+		return nil
+	}
+
+	var file, err = os.Open(filepath.Join(root, p.ignoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error reading ignore file: %w", err)
+	}
+	defer file.Close()
+
+	var rules []pruneRule
+	var scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue // negation is not supported
+		}
+		if rule, err := newPruneRule(line); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading ignore file: %w", err)
+	}
+	p.rootRules[root] = rules
+	return nil
+}
+
+// pruned reports if path, or a directory between it and root, matches a rule. isDir is only called when it matters.
+func (p *pruner) pruned(root, path string, isDir func() bool) bool {
+	if p == nil || len(p.rules) == 0 && len(p.rootRules[root]) == 0 {
+		return false
+	}
+
+	var rel, err = filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+
+	var segments = strings.Split(rel, string(filepath.Separator))
+	for i := range segments {
+		var sub = filepath.Join(segments[:i+1]...)
+		var last = i == len(segments)-1
+		for _, rules := range [][]pruneRule{p.rules, p.rootRules[root]} {
+			for _, rule := range rules {
+				if rule.match(sub, !last || rule.dirOnly && isDir()) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruner(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, ".watchignore"), []byte("# generated\n\n*.log\n/build/\n!keep.log\n"), os.ModePerm))
+
+	var p, err = newPruner([]string{"node_modules", "web/dist"}, ".watchignore")
+	assert.NoError(t, err)
+	assert.NoError(t, p.load(root))
+	assert.NoError(t, p.load(t.TempDir())) // no ignore file
+
+	var isDir = func() bool { return true }
+	var isFile = func() bool { return false }
+	var cases = map[string]bool{
+		"node_modules":                     true,
+		filepath.Join("a", "node_modules"): true,
+		filepath.Join("node_modules", "x"): true,
+		filepath.Join("web", "dist"):       true,
+		filepath.Join("x", "web", "dist"):  false, // anchored to the root
+		"app.log":                          true,
+		filepath.Join("a", "keep.log"):     true, // negation is skipped
+		"build":                            true,
+		filepath.Join("a", "build"):        false,
+		"main.go":                          false,
+		".":                                false,
+	}
+	for rel, pruned := range cases {
+		assert.Equal(t, pruned, p.pruned(root, filepath.Join(root, rel), isDir), rel)
+	}
+	assert.False(t, p.pruned(root, filepath.Join(root, "build"), isFile)) // only directories
+
+	_, err = newPruner([]string{"["}, "")
+	assert.Error(t, err)
+	_, err = newPruner([]string{"/"}, "")
+	assert.Error(t, err)
+}
+
+func TestWatcherPrune(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "pkg"), os.ModePerm))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.tmp\n"), os.ModePerm))
+
+	var watcher, err = NewWatcher(t.Context(), 255, WithPrune("node_modules"), WithIgnoreFile(".gitignore"))
+	assert.NoError(t, err)
+	defer watcher.Close()
+	assert.NoError(t, watcher.Add(dir))
+
+	// pruned directories are not watched
+	assert.True(t, watcher.dw.dirs.has(filepath.Join(dir, "src")))
+	assert.False(t, watcher.dw.dirs.has(filepath.Join(dir, "node_modules")))
+	assert.False(t, watcher.dw.dirs.has(filepath.Join(dir, "node_modules", "pkg")))
+
+	// and nothing in them, or matching the ignore file, is reported
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "node_modules"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "node_modules", "index.js"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "scratch.tmp"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", "main.go"), []byte{}, os.ModePerm))
+
+	var event = <-watcher.Events()
+	assert.Equal(t, filepath.Join(dir, "src", "main.go"), event.AbsolutePath)
+	assert.False(t, watcher.dw.dirs.has(filepath.Join(dir, "src", "node_modules")))
+
+	_, err = NewWatcher(t.Context(), 0, WithPrune("["))
+	assert.Error(t, err)
+}
+
+// limitBackend is a watchBackend that refuses every watch like inotify does once max_user_watches is reached.
+type limitBackend struct {
+	watchBackend
+}
+
+func (limitBackend) Add(string) error {
+	return syscall.ENOSPC
+}
+
+func TestWatchLimit(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the watch limit is only recognised on linux")
+	}
+
+	var wd = newWatchedDirs(limitBackend{}, 0)
+	var _, err = wd.addRoot(t.TempDir())
+	assert.ErrorIs(t, err, ErrWatchLimit)
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	assert.Contains(t, err.Error(), "max_user_watches")
+}
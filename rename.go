@@ -0,0 +1,79 @@
+package path
+
+import (
+	"io/fs"
+	"time"
+)
+
+// renameTracker pairs the RENAME fsnotify reports for the old name of a moved path with the CREATE for its new name.
+// Renames are held for window waiting for their CREATE. The pair is matched on inode where the platform has them,
+// otherwise the oldest held rename is used as fsnotify reports the two back to back.
+type renameTracker struct {
+	window  time.Duration
+	pending []pendingRename
+}
+
+// pendingRename is the old name of a moved path waiting for its new name.
+type pendingRename struct {
+	path     string
+	info     fs.FileInfo // last known metadata, nil if it was never seen
+	deadline time.Time
+}
+
+func newRenameTracker(window time.Duration) *renameTracker {
+	return &renameTracker{window: window}
+}
+
+// renamed holds oldPath until its new name shows up or window passes.
+func (rt *renameTracker) renamed(oldPath string, info fs.FileInfo, now time.Time) {
+	for _, p := range rt.pending {
+		if p.path == oldPath { // some backends report both the watch and its parent
+			return
+		}
+	}
+	rt.pending = append(rt.pending, pendingRename{path: oldPath, info: info, deadline: now.Add(rt.window)})
+}
+
+// created looks for the held rename that the newly created path described by info was moved from, it is removed if found.
+func (rt *renameTracker) created(info fs.FileInfo) (pendingRename, bool) {
+	var newInode, hasInode = inode(info)
+
+	for i, p := range rt.pending {
+		if p.info != nil && info != nil && p.info.IsDir() != info.IsDir() {
+			continue
+		}
+
+		if hasInode {
+			if oldInode, ok := inode(p.info); !ok || oldInode != newInode {
+				continue
+			}
+		}
+
+		rt.pending = append(rt.pending[:i], rt.pending[i+1:]...)
+		return p, true
+	}
+	return pendingRename{}, false
+}
+
+// expired removes and returns the renames that were not paired within window, those paths were moved out of the watch.
+func (rt *renameTracker) expired(now time.Time) []pendingRename {
+	var expired []pendingRename
+	var waiting = rt.pending[:0]
+	for _, p := range rt.pending {
+		if now.Before(p.deadline) {
+			waiting = append(waiting, p)
+		} else {
+			expired = append(expired, p)
+		}
+	}
+	rt.pending = waiting
+	return expired
+}
+
+// next returns when the oldest held rename expires, false if nothing is held.
+func (rt *renameTracker) next() (time.Time, bool) {
+	if len(rt.pending) == 0 {
+		return time.Time{}, false
+	}
+	return rt.pending[0].deadline, true
+}
@@ -0,0 +1,135 @@
+package path
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameTracker(t *testing.T) {
+	t.Parallel()
+
+	var start = time.Date(2022, 06, 01, 0, 0, 0, 0, time.UTC)
+	var rt = newRenameTracker(time.Second)
+
+	mp3, err := os.Lstat("./testdata/one/file.mp3")
+	assert.NoError(t, err)
+	mp4, err := os.Lstat("./testdata/one/file.mp4")
+	assert.NoError(t, err)
+	dir, err := os.Lstat("./testdata/one")
+	assert.NoError(t, err)
+
+	var _, held = rt.next()
+	assert.False(t, held)
+
+	rt.renamed("old.mp3", mp3, start)
+	rt.renamed("old.mp3", mp3, start) // reported twice
+	rt.renamed("old.mp4", mp4, start.Add(time.Millisecond*500))
+	assert.Len(t, rt.pending, 2)
+
+	deadline, held := rt.next()
+	assert.True(t, held)
+	assert.Equal(t, start.Add(time.Second), deadline)
+
+	// a directory never pairs with a file
+	_, moved := rt.created(dir)
+	assert.False(t, moved)
+
+	p, moved := rt.created(mp4)
+	assert.True(t, moved)
+	if _, ok := inode(mp4); ok {
+		assert.Equal(t, "old.mp4", p.path) // matched on inode
+	} else {
+		assert.Equal(t, "old.mp3", p.path) // oldest first
+	}
+
+	assert.Empty(t, rt.expired(start.Add(time.Millisecond*999)))
+	var expired = rt.expired(start.Add(time.Hour))
+	assert.Len(t, expired, 1)
+
+	_, held = rt.next()
+	assert.False(t, held)
+}
+
+func TestWatchDirRenameTracking(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	var dir = filepath.Join(root, "watched")
+	var outside = filepath.Join(root, "outside")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), os.ModePerm))
+	assert.NoError(t, os.Mkdir(outside, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), os.ModePerm))
+
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 1, false, files, errs, WithRenameTracking(time.Second), WithClock(clock))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	// a move within the watch is a single event
+	assert.NoError(t, os.Rename(filepath.Join(dir, "a.txt"), filepath.Join(dir, "sub", "c.txt")))
+
+	var event = <-files
+	assert.Equal(t, fsnotify.Rename, event.Op)
+	assert.Equal(t, filepath.Join(dir, "a.txt"), event.OldPath)
+	assert.Equal(t, filepath.Join(dir, "sub", "c.txt"), event.NewPath)
+	assert.Equal(t, event.NewPath, event.AbsolutePath)
+	assert.Equal(t, int64(1), event.FileInfo.Size())
+
+	// a move out of the watch is published once the window passes, with the last known metadata
+	assert.NoError(t, os.Rename(filepath.Join(dir, "b.txt"), filepath.Join(outside, "b.txt")))
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to process the event
+
+	select {
+	case event := <-files:
+		assert.Fail(t, "move out published before the window passed", event.AbsolutePath)
+	default:
+	}
+	clock.Advance(time.Second)
+
+	event = <-files
+	assert.Equal(t, fsnotify.Rename, event.Op)
+	assert.Equal(t, filepath.Join(dir, "b.txt"), event.OldPath)
+	assert.Empty(t, event.NewPath)
+	assert.Equal(t, event.OldPath, event.AbsolutePath)
+	assert.Equal(t, "b.txt", event.FileInfo.Name())
+
+	// a move into the watch is a create
+	assert.NoError(t, os.Rename(filepath.Join(outside, "b.txt"), filepath.Join(dir, "b.txt")))
+	event = <-files
+	assert.Equal(t, fsnotify.Create, event.Op)
+	assert.Equal(t, filepath.Join(dir, "b.txt"), event.AbsolutePath)
+	assert.Empty(t, event.OldPath)
+
+	if runtime.GOOS == "linux" {
+		// a renamed directory keeps being watched under its new name
+		assert.NoError(t, os.Rename(filepath.Join(dir, "sub"), filepath.Join(dir, "sub2")))
+		event = <-files
+		assert.Equal(t, fsnotify.Rename, event.Op)
+		assert.Equal(t, filepath.Join(dir, "sub2"), event.NewPath)
+
+		time.Sleep(time.Millisecond * 100)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub2", "d.txt"), []byte{}, os.ModePerm))
+		event = <-files
+		assert.Equal(t, fsnotify.Create, event.Op)
+		assert.Equal(t, filepath.Join(dir, "sub2", "d.txt"), event.AbsolutePath)
+	}
+
+	cancel()
+}
@@ -0,0 +1,127 @@
+package path
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchState is what WithStateFile keeps between runs: the roots that were watched and the last known metadata of
+// everything under them.
+type watchState struct {
+	Roots   []string              `json:"roots"`
+	Entries map[string]stateEntry `json:"entries"`
+}
+
+// stateEntry is the metadata of one path in a watchState.
+type stateEntry struct {
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Mode    fs.FileMode `json:"mode"`
+	Inode   uint64      `json:"inode,omitempty"`
+}
+
+func newStateEntry(info fs.FileInfo) stateEntry {
+	var ino, _ = inode(info)
+	return stateEntry{Size: info.Size(), ModTime: info.ModTime(), Mode: info.Mode(), Inode: ino}
+}
+
+// changed reports if the path was modified between se and current.
+func (se stateEntry) changed(current stateEntry) bool {
+	return se.Size != current.Size || !se.ModTime.Equal(current.ModTime) || se.Inode != current.Inode
+}
+
+// loadState reads a state file, a missing file is an empty state.
+func loadState(file string) (watchState, error) {
+	var state = watchState{Entries: make(map[string]stateEntry)}
+
+	var data, err = os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("error reading state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error decoding state file %s: %w", file, err)
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]stateEntry)
+	}
+	return state, nil
+}
+
+// save writes state to file, through a temporary file so a crash never leaves half a state behind.
+func (ws watchState) save(file string) error {
+	var data, err = json.Marshal(ws)
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error saving state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck // it is gone after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("error saving state file: %w", err)
+	}
+	return nil
+}
+
+// take removes and returns the entries of root, false if root was not watched when the state was saved. A directory
+// root is left out, it is watched again and not among what is found in it.
+func (ws *watchState) take(root string) (map[string]stateEntry, bool) {
+	var i = slices.Index(ws.Roots, root)
+	if i < 0 {
+		return nil, false
+	}
+	ws.Roots = slices.Delete(ws.Roots, i, i+1)
+
+	var entries = make(map[string]stateEntry)
+	var prefix = root + string(filepath.Separator)
+	for p, entry := range ws.Entries {
+		if p != root && !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		delete(ws.Entries, p)
+		if p != root || !entry.Mode.IsDir() {
+			entries[p] = entry
+		}
+	}
+	return entries, true
+}
+
+// saveState writes the roots and metadata cache to the state file.
+func (dw *dirWatcher) saveState() error {
+	var state = watchState{Roots: dw.dirs.rootList(), Entries: make(map[string]stateEntry)}
+	for p, info := range dw.cache.snapshot() {
+		state.Entries[p] = newStateEntry(info)
+	}
+	return state.save(dw.config.stateFile)
+}
+
+// catchUp publishes what changed under root while nobody was watching, found is what is there now. Without a saved
+// state for root there is nothing to compare to. With WithInitialEvents everything found is already published, so
+// only the removals are.
+func (dw *dirWatcher) catchUp(root string, found []Entry) {
+	var saved, has = dw.saved.take(root)
+	if !has {
+		return
+	}
+	dw.reconcile(saved, found, !dw.config.initialEvents)
+}
@@ -0,0 +1,163 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchState(t *testing.T) {
+	t.Parallel()
+
+	var file = filepath.Join(t.TempDir(), "state.json")
+
+	state, err := loadState(file)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Roots)
+	assert.Empty(t, state.Entries)
+
+	var mtime = time.Date(2022, 06, 01, 0, 0, 0, 0, time.UTC)
+	state = watchState{
+		Roots: []string{"/a", "/b"},
+		Entries: map[string]stateEntry{
+			"/a/one":   {Size: 1, ModTime: mtime, Mode: 0o644, Inode: 10},
+			"/a/two":   {Size: 2, ModTime: mtime, Mode: 0o644},
+			"/ab/skip": {Size: 3, ModTime: mtime, Mode: 0o644},
+		},
+	}
+	assert.NoError(t, state.save(file))
+
+	loaded, err := loadState(file)
+	assert.NoError(t, err)
+	assert.Equal(t, state, loaded)
+
+	entries, has := loaded.take("/a")
+	assert.True(t, has)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, []string{"/b"}, loaded.Roots)
+	assert.Len(t, loaded.Entries, 1)
+
+	_, has = loaded.take("/a")
+	assert.False(t, has)
+
+	assert.False(t, entries["/a/one"].changed(entries["/a/one"]))
+	assert.True(t, entries["/a/one"].changed(stateEntry{Size: 1, ModTime: mtime, Mode: 0o644, Inode: 11}))
+
+	assert.NoError(t, os.WriteFile(file, []byte("{"), 0o600))
+	_, err = loadState(file)
+	assert.Error(t, err)
+}
+
+func TestWatcherStateFile(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var stateFile = filepath.Join(t.TempDir(), "state.json")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("a"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "removed.txt"), []byte("bb"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "same.txt"), []byte{}, os.ModePerm))
+
+	// the first run has nothing to catch up on
+	watcher, err := NewWatcher(t.Context(), 0, WithStateFile(stateFile, 0))
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Add(dir))
+	select {
+	case event := <-watcher.Events():
+		assert.Fail(t, "event on the first run", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 100):
+	}
+	assert.NoError(t, watcher.Close())
+	assert.FileExists(t, stateFile)
+
+	// changes while nothing is watching
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "changed.txt"), []byte("aaa"), os.ModePerm))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "removed.txt")))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "created.txt"), []byte{}, os.ModePerm))
+
+	watcher, err = NewWatcher(t.Context(), 0, WithStateFile(stateFile, 0))
+	assert.NoError(t, err)
+	defer watcher.Close()
+	assert.NoError(t, watcher.Add(dir))
+
+	var seen = make(map[string]WatchEvent)
+	for len(seen) < 3 {
+		var event = <-watcher.Events()
+		seen[filepath.Base(event.AbsolutePath)] = event
+	}
+	assert.Equal(t, fsnotify.Write, seen["changed.txt"].Op)
+	assert.Equal(t, fsnotify.Create, seen["created.txt"].Op)
+	assert.Equal(t, fsnotify.Remove, seen["removed.txt"].Op)
+	assert.Equal(t, int64(2), seen["removed.txt"].FileInfo.Size())
+
+	select {
+	case event := <-watcher.Events():
+		assert.Fail(t, "unexpected event", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestWatcherStateFileRoot(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("directory modes can not be changed")
+	}
+
+	var dir = t.TempDir()
+	var stateFile = filepath.Join(t.TempDir(), "state.json")
+
+	// the chmod puts the root in the saved state
+	watcher, err := NewWatcher(t.Context(), 0, WithStateFile(stateFile, 0))
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Add(dir))
+	assert.NoError(t, os.Chmod(dir, 0o700))
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, watcher.Close())
+
+	var state, _ = loadState(stateFile)
+	assert.Contains(t, state.Entries, dir)
+
+	// the root is not reported removed and is still watched after the restart
+	watcher, err = NewWatcher(t.Context(), 0, WithStateFile(stateFile, 0))
+	assert.NoError(t, err)
+	defer watcher.Close()
+	assert.NoError(t, watcher.Add(dir))
+
+	var file = filepath.Join(dir, "after.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, os.ModePerm))
+	select {
+	case event := <-watcher.Events():
+		assert.Equal(t, fsnotify.Create, event.Op)
+		assert.Equal(t, file, event.AbsolutePath)
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "no event after the restart")
+	}
+}
+
+func TestWatcherStateFileInterval(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var stateFile = filepath.Join(t.TempDir(), "state.json")
+	var clock = newFakeClock()
+
+	watcher, err := NewWatcher(t.Context(), 0, WithStateFile(stateFile, time.Minute), WithClock(clock))
+	assert.NoError(t, err)
+	defer watcher.Close()
+	assert.NoError(t, watcher.Add(dir))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte{}, os.ModePerm))
+	<-watcher.Events()
+
+	assert.NoFileExists(t, stateFile)
+	clock.Advance(time.Minute)
+
+	assert.Eventually(t, func() bool {
+		var state, err = loadState(stateFile)
+		return err == nil && len(state.Entries) == 1
+	}, time.Second*5, time.Millisecond*10)
+}
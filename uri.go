@@ -0,0 +1,78 @@
+package path
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// fileScheme is the URI scheme handled by fromFileURI.
+const fileScheme = "file"
+
+// isFileURI reports if inputPath is a file:// URI.
+func isFileURI(inputPath string) bool {
+	return strings.HasPrefix(strings.ToLower(inputPath), fileScheme+"://")
+}
+
+// fromFileURI converts a file:// URI such as file:///home/x/My%20File.jpg to a local path. Paths that are not
+// file URIs are returned unchanged. Only local hosts (empty or localhost) are accepted, except on windows where
+// any other host is treated as a UNC share.
+func fromFileURI(inputPath string) (string, error) {
+	inputPath = strings.TrimSpace(inputPath)
+	if !isFileURI(inputPath) {
+		return inputPath, nil
+	}
+
+	var uri, err = url.Parse(inputPath)
+	if err != nil {
+		return "", fmt.Errorf("error parsing file uri: %s, error: %w", inputPath, err)
+	}
+
+	if uri.RawQuery != "" || uri.Fragment != "" {
+		return "", fmt.Errorf("file uri: %s must not have a query or fragment", inputPath)
+	}
+
+	var p = uri.Path // already percent-decoded
+	if p == "" {
+		return "", fmt.Errorf("file uri: %s has no path", inputPath)
+	}
+
+	var host = uri.Hostname()
+	if host != "" && !strings.EqualFold(host, "localhost") {
+		if runtime.GOOS != "windows" {
+			return "", fmt.Errorf("file uri: %s has non local host: %s", inputPath, host)
+		}
+		return `\\` + host + filepath.FromSlash(p), nil
+	}
+
+	// file:///C:/dir is /C:/dir after parsing
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+
+	return filepath.FromSlash(p), nil
+}
+
+// URI returns the entry as a file:// URI with the path percent-encoded, the reverse of passing a file URI to NewEntry.
+func (e *Entry) URI() string {
+	var p = filepath.ToSlash(e.AbsolutePath)
+	var host string
+
+	if runtime.GOOS == "windows" {
+		if strings.HasPrefix(p, "//") { // UNC path: //host/share
+			var parts = strings.SplitN(p[2:], "/", 2)
+			host = parts[0]
+			p = "/"
+			if len(parts) == 2 {
+				p += parts[1]
+			}
+		} else if !strings.HasPrefix(p, "/") {
+			p = "/" + p
+		}
+	}
+
+	var uri = url.URL{Scheme: fileScheme, Host: host, Path: p}
+	return uri.String()
+}
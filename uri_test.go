@@ -0,0 +1,74 @@
+package path
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFileURI(t *testing.T) {
+	t.Parallel()
+
+	var p, err = fromFileURI("./testdata/one")
+	assert.NoError(t, err)
+	assert.Equal(t, "./testdata/one", p)
+
+	_, err = fromFileURI("file:///tmp/x?query=1")
+	assert.Error(t, err)
+
+	_, err = fromFileURI("file://")
+	assert.Error(t, err)
+
+	_, err = fromFileURI("file:///tmp/%zz")
+	assert.Error(t, err)
+
+	if runtime.GOOS == "windows" {
+		p, err = fromFileURI("file:///C:/My%20Dir/file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, `C:\My Dir\file.txt`, p)
+
+		p, err = fromFileURI("file://server/share/file.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, `\\server\share\file.txt`, p)
+		return
+	}
+
+	p, err = fromFileURI("file:///home/x/My%20File.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/x/My File.jpg", p)
+
+	p, err = fromFileURI("FILE://localhost/home/x/a%25b")
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/x/a%b", p)
+
+	_, err = fromFileURI("file://example.com/home/x")
+	assert.Contains(t, err.Error(), "non local host: example.com")
+}
+
+func TestEntryURI(t *testing.T) {
+	t.Parallel()
+
+	var entry, err = NewEntry("./testdata/ogCGs91VSA5FBjJdgE8eeLSngbebPXyDCICZ7I~tplv-f5insbecw7-1 720 720.jpg", 0)
+	assert.NoError(t, err)
+
+	var uri = entry.URI()
+	assert.True(t, strings.HasPrefix(uri, "file:///"))
+	assert.True(t, strings.HasSuffix(uri, "/ogCGs91VSA5FBjJdgE8eeLSngbebPXyDCICZ7I~tplv-f5insbecw7-1%20720%20720.jpg"))
+
+	// round trip
+	fromURI, err := NewEntry(uri, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, entry.AbsolutePath, fromURI.AbsolutePath)
+	assert.Equal(t, entry.FileInfo.Size(), fromURI.FileInfo.Size())
+
+	// uris work anywhere a path does
+	dir, err := NewEntry(filepath.Dir(entry.AbsolutePath), 0)
+	assert.NoError(t, err)
+
+	files, err := List(dir.URI()+"/one", 1, false, MustBeDir())
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+}
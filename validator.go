@@ -0,0 +1,183 @@
+package path
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// globMeta are the characters filepath.Match treats as special.
+const globMeta = `*?[`
+
+// EntryValidator constrains the input path of an Entry. Validators may be passed to NewEntry and List alongside
+// EntriesFilters, where they only apply to inputPath and accept every child, or attached to an Entry flag with
+// WithValidators so that flag.Parse reports a violation to the user.
+type EntryValidator interface {
+	EntriesFilter
+	// prepare is called with the absolute input path before it is stat'd.
+	prepare(absolutePath string) error
+	// validate is called with the stat'd root entry.
+	validate(entry Entry) error
+}
+
+// baseValidator provides the no-op methods so each validator only needs to implement the phase it cares about.
+type baseValidator struct{}
+
+func (baseValidator) filter(Entry) bool    { return true }
+func (baseValidator) prepare(string) error { return nil }
+func (baseValidator) validate(Entry) error { return nil }
+
+// WithValidators attaches validators to an Entry that is used as a flag, they are run on every call to Set.
+func (e *Entry) WithValidators(validators ...EntryValidator) *Entry {
+	e.validators = append(e.validators, validators...)
+	return e
+}
+
+// validatorsOf pulls the validators out of a list of filters.
+func validatorsOf(filters []EntriesFilter) []EntryValidator {
+	var validators []EntryValidator
+	for _, fn := range filters {
+		if v, ok := fn.(EntryValidator); ok {
+			validators = append(validators, v)
+		}
+	}
+	return validators
+}
+
+// MustExistValidator fails if the input path does not exist.
+type MustExistValidator struct {
+	baseValidator
+}
+
+func MustExist() MustExistValidator {
+	return MustExistValidator{}
+}
+
+func (MustExistValidator) prepare(absolutePath string) error {
+	if _, err := os.Lstat(absolutePath); err == nil {
+		return nil
+	}
+
+	// globbed input will not stat, so it exists if anything matches it
+	if matches, err := filepath.Glob(absolutePath); err == nil && len(matches) > 0 {
+		return nil
+	}
+	return fmt.Errorf("path %s does not exist: %w", absolutePath, fs.ErrNotExist)
+}
+
+// MustBeDirValidator fails if the input path is not a directory.
+type MustBeDirValidator struct {
+	baseValidator
+}
+
+func MustBeDir() MustBeDirValidator {
+	return MustBeDirValidator{}
+}
+
+func (MustBeDirValidator) validate(entry Entry) error {
+	if !entry.IsDir() {
+		return fmt.Errorf("path %s is not a directory", entry.AbsolutePath)
+	}
+	return nil
+}
+
+// MustBeFileValidator fails if the input path is not a regular file.
+type MustBeFileValidator struct {
+	baseValidator
+}
+
+func MustBeFile() MustBeFileValidator {
+	return MustBeFileValidator{}
+}
+
+func (MustBeFileValidator) validate(entry Entry) error {
+	if !entry.FileInfo.Mode().IsRegular() {
+		return fmt.Errorf("path %s is not a regular file", entry.AbsolutePath)
+	}
+	return nil
+}
+
+// MustBeReadableValidator fails if the input path cannot be opened for reading by the current user.
+type MustBeReadableValidator struct {
+	baseValidator
+}
+
+func MustBeReadable() MustBeReadableValidator {
+	return MustBeReadableValidator{}
+}
+
+func (MustBeReadableValidator) validate(entry Entry) error {
+	var f, err = os.Open(entry.AbsolutePath)
+	if err != nil {
+		return fmt.Errorf("path %s is not readable: %w", entry.AbsolutePath, err)
+	}
+	return f.Close()
+}
+
+// MustBeWritableValidator fails if the input path cannot be written to by the current user.
+// For directories this means a file can be created within it.
+type MustBeWritableValidator struct {
+	baseValidator
+}
+
+func MustBeWritable() MustBeWritableValidator {
+	return MustBeWritableValidator{}
+}
+
+func (MustBeWritableValidator) validate(entry Entry) error {
+	if entry.IsDir() {
+		var f, err = os.CreateTemp(entry.AbsolutePath, ".path-writable-*")
+		if err != nil {
+			return fmt.Errorf("path %s is not writable: %w", entry.AbsolutePath, err)
+		}
+		return errors.Join(f.Close(), os.Remove(f.Name()))
+	}
+
+	var f, err = os.OpenFile(entry.AbsolutePath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("path %s is not writable: %w", entry.AbsolutePath, err)
+	}
+	return f.Close()
+}
+
+// CreateIfMissingValidator creates the input path if it does not exist. If mode has fs.ModeDir set a directory
+// is created, otherwise an empty file. Missing parent directories are always created.
+type CreateIfMissingValidator struct {
+	baseValidator
+	mode fs.FileMode
+}
+
+func CreateIfMissing(mode fs.FileMode) CreateIfMissingValidator {
+	return CreateIfMissingValidator{mode: mode}
+}
+
+func (cv CreateIfMissingValidator) prepare(absolutePath string) error {
+	if _, err := os.Lstat(absolutePath); !errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	// never create a literal glob pattern
+	if strings.ContainsAny(absolutePath, globMeta) {
+		return nil
+	}
+
+	if cv.mode.IsDir() {
+		if err := os.MkdirAll(absolutePath, cv.mode.Perm()); err != nil {
+			return fmt.Errorf("error creating directory %s: %w", absolutePath, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absolutePath), os.ModePerm); err != nil {
+		return fmt.Errorf("error creating parent directory of %s: %w", absolutePath, err)
+	}
+
+	var f, err = os.OpenFile(absolutePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, cv.mode.Perm())
+	if err != nil {
+		return fmt.Errorf("error creating file %s: %w", absolutePath, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,126 @@
+package path
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMustExist(t *testing.T) {
+	t.Parallel()
+
+	var _, err = NewEntry("./testdata/one", 0, MustExist())
+	assert.NoError(t, err)
+
+	_, err = NewEntry("./testdata/*", 1, MustExist())
+	assert.NoError(t, err)
+
+	_, err = NewEntry("./testdata/notexist", 0, MustExist())
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Contains(t, err.Error(), "does not exist")
+}
+
+func TestMustBeDirAndFile(t *testing.T) {
+	t.Parallel()
+
+	var _, err = NewEntry("./testdata/one", 0, MustBeDir())
+	assert.NoError(t, err)
+
+	_, err = NewEntry("./testdata/one/file.txt", 0, MustBeDir())
+	assert.Contains(t, err.Error(), "is not a directory")
+
+	_, err = NewEntry("./testdata/one/file.txt", 0, MustBeFile())
+	assert.NoError(t, err)
+
+	_, err = NewEntry("./testdata/one", 0, MustBeFile())
+	assert.Contains(t, err.Error(), "is not a regular file")
+}
+
+func TestMustBeReadableWritable(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+
+	var _, err = NewEntry(dir, 0, MustBeReadable(), MustBeWritable())
+	assert.NoError(t, err)
+
+	_, err = NewEntry(file, 0, MustBeReadable(), MustBeWritable())
+	assert.NoError(t, err)
+
+	// the temp file used to check writability must be cleaned up
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 { // root ignores permission bits
+		return
+	}
+
+	assert.NoError(t, os.Chmod(file, 0o400))
+	_, err = NewEntry(file, 0, MustBeWritable())
+	assert.Contains(t, err.Error(), "is not writable")
+
+	assert.NoError(t, os.Chmod(file, 0o200))
+	_, err = NewEntry(file, 0, MustBeReadable())
+	assert.Contains(t, err.Error(), "is not readable")
+}
+
+func TestCreateIfMissing(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+
+	var entry, err = NewEntry(filepath.Join(dir, "a", "b"), 0, CreateIfMissing(fs.ModeDir|0o755), MustBeDir())
+	assert.NoError(t, err)
+	assert.True(t, entry.IsDir())
+
+	entry, err = NewEntry(filepath.Join(dir, "c", "file.txt"), 0, CreateIfMissing(0o644), MustBeFile())
+	assert.NoError(t, err)
+	assert.False(t, entry.IsDir())
+	assert.Equal(t, int64(0), entry.FileInfo.Size())
+
+	// existing paths are left alone
+	assert.NoError(t, os.WriteFile(entry.AbsolutePath, []byte("keep"), 0o644))
+	entry, err = NewEntry(entry.AbsolutePath, 0, CreateIfMissing(0o644))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), entry.FileInfo.Size())
+
+	// globs are never created
+	_, err = NewEntry(filepath.Join(dir, "*.mp3"), 0, CreateIfMissing(0o644))
+	assert.Error(t, err)
+	_, err = os.Lstat(filepath.Join(dir, "*.mp3"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestValidatorsDoNotFilterChildren(t *testing.T) {
+	t.Parallel()
+
+	var files, err = List("./testdata/", 3, false, MustExist(), MustBeDir())
+	assert.NoError(t, err)
+	assert.Len(t, files, 8)
+}
+
+func TestValidatorFlag(t *testing.T) {
+	t.Parallel()
+
+	var entry Entry
+	var flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(entry.WithValidators(MustBeDir()), "path", "path to files")
+
+	var err = flags.Parse([]string{"-path", "./testdata/one/file.txt"})
+	assert.Contains(t, err.Error(), `invalid value "./testdata/one/file.txt" for flag -path`)
+	assert.Contains(t, err.Error(), "is not a directory")
+
+	assert.NoError(t, flags.Parse([]string{"-path", "./testdata/one"}))
+	assert.True(t, entry.IsDir())
+}
@@ -2,123 +2,137 @@ package path
 
 import (
 	"context"
-	"fmt"
 	"io/fs"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
-	"slices"
-
 	"github.com/fsnotify/fsnotify"
 )
 
-// WatchEvent is a wrapper for Entry and fsnotify.Op.
+// WatchEvent is a wrapper for Entry and fsnotify.Op. The Entry describes the path itself, Children are not listed.
+// A removed path is described by its last known metadata, or only by its name when there is none.
+// With WithRenameTracking a move is published as a single fsnotify.Rename event with OldPath and NewPath set,
+// NewPath is empty when the path was moved out of the watch and Entry then holds its last known metadata.
 type WatchEvent struct {
 	Entry
 	fsnotify.Op
+	OldPath string
+	NewPath string
+}
+
+// OpString returns the names of the Ops of the event like fsnotify.Op.String, Modified and Ready included.
+func (we WatchEvent) OpString() string {
+	var names []string
+	if op := we.Op &^ (Modified | Ready); op != 0 {
+		names = append(names, op.String())
+	}
+	if we.Has(Modified) {
+		names = append(names, "MODIFIED")
+	}
+	if we.Has(Ready) {
+		names = append(names, "READY")
+	}
+	if len(names) == 0 {
+		return we.Op.String()
+	}
+	return strings.Join(names, "|")
+}
+
+// String returns the Ops and the path of the event, e.g. "CREATE|WRITE /tmp/file.txt".
+func (we WatchEvent) String() string {
+	return we.OpString() + " " + we.AbsolutePath
 }
 
 // WatchDir will watch a directory indefinitely for changes and publish them on the given files channel with optional filters.
-// nolint: gocognit, funlen
+// With a recursiveDepth > 0 every directory below inputPath is watched as well, directories created or removed while
+// watching are added and dropped as they come and go. The root is always watched, includeRoot is kept for
+// compatibility. WatchDir blocks until ctx is done and then closes files and errors. If the watch can not be started
+// the error is sent on errors, unless ctx is done, and neither channel is closed. WatchDirOptions takes other
+// WatchOptions as well.
 func WatchDir(ctx context.Context, inputPath string, recursiveDepth uint8, includeRoot bool, files chan WatchEvent, errors chan error, filters ...WatchFilter) {
+	WatchDirOptions(ctx, inputPath, recursiveDepth, includeRoot, files, errors, WithFilters(filters...))
+}
 
-	inputPath = filepath.Clean(strings.TrimSpace(inputPath))
+// WatchDirOptions is WatchDir with WatchOptions such as WithDebounce, filters are WatchOptions too.
+// inputPath may be a glob pattern, see Watcher.Add, or not exist yet with WithMissingPaths. WithWatchDepth limits how
+// deep the tree is watched. NewWatcher offers more control.
+func WatchDirOptions(ctx context.Context, inputPath string, recursiveDepth uint8, includeRoot bool, files chan WatchEvent, errors chan error, opts ...WatchOption) {
 
-	var inputEntry, err = NewEntry(inputPath, recursiveDepth)
+	var watcher, err = NewWatcher(ctx, recursiveDepth, opts...)
 	if err != nil {
-		errors <- fmt.Errorf("error with inputPath: %w", err)
+		select {
+		case errors <- err:
+		case <-ctx.Done():
+		}
 		return
 	}
+	defer watcher.Close()
 
-	defer close(files)
-	defer close(errors)
-
-	// Create new watcher.
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		errors <- fmt.Errorf("error creating NewWatcher: %w", err)
+	if err := watcher.Add(inputPath); err != nil {
+		select {
+		case errors <- err:
+		case <-ctx.Done():
+		}
 		return
 	}
-	defer watcher.Close()
 
-	// Start listening for events.
-	var wait = make(chan struct{})
-	go func() {
-		defer close(wait)
+	defer close(files)
+	defer close(errors)
+
+	forward(ctx, watcher, files, errors, func(event WatchEvent) (WatchEvent, bool) { return event, true })
+}
 
-	EventsLoop:
-		for {
+// forward sends the events and errors of watcher on files and errors until the watcher is closed or ctx is done.
+// Each event is passed through publish first, it is dropped when publish reports false.
+func forward(ctx context.Context, watcher *Watcher, files chan WatchEvent, errors chan error, publish func(WatchEvent) (WatchEvent, bool)) {
+	var events, watchErrors = watcher.Events(), watcher.Errors()
+	for events != nil || watchErrors != nil {
+		select {
+		case event, open := <-events:
+			if !open {
+				events = nil
+				continue
+			}
+			var ok bool
+			if event, ok = publish(event); !ok {
+				continue
+			}
 			select {
+			case files <- event:
 			case <-ctx.Done():
 				return
-
-			case event, open := <-watcher.Events:
-				if !open {
-					return
-				}
-				// try all the filter funcs
-				for _, fn := range filters {
-					var accepted, err = fn.filter(event)
-					if err != nil {
-						errors <- err
-					}
-					if !accepted {
-						continue EventsLoop
-					}
-				}
-				if e, err := NewEntry(event.Name, recursiveDepth); err != nil {
-					errors <- err
-				} else {
-					files <- WatchEvent{Entry: e, Op: event.Op}
-				}
-
-			case err, open := <-watcher.Errors:
-				if !open {
-					return
-				}
-				errors <- err
 			}
-		}
-	}()
-
-	var entries []Entry
-	if recursiveDepth > 0 {
-		var rootEntry, err = NewEntry(inputPath, 255, NewDirEntitiesFilter())
-		if err != nil {
-			errors <- fmt.Errorf("error adding path to watcher: %w", err)
-			return
-		}
 
-		entries, err = rootEntry.Flatten(includeRoot)
-		if err != nil {
-			errors <- fmt.Errorf("error flattening entry: %w", err)
-			return
-		}
-
-		entries = append(entries, inputEntry)
-	} else {
-		entries = []Entry{inputEntry}
-	}
-
-	// Add paths.
-	for _, dir := range entries {
-		err = watcher.Add(dir.AbsolutePath)
-		if err != nil {
-			errors <- fmt.Errorf("error adding path to watcher: %w", err)
-			return
+		case err, open := <-watchErrors:
+			if !open {
+				watchErrors = nil
+				continue
+			}
+			select {
+			case errors <- err:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}
-
-	<-wait
 }
 
 //////////////////////////////////////////////////////////////////
 
-// WatchFilter interface facilitates filtering of file events.
+// WatchFilter interface facilitates filtering of file events. Filters are given the WatchEvent that will be
+// published. Filters on the path or Op run first, filters on metadata only run for events that passed them and share
+// one Entry. For paths that no longer exist it holds their last known metadata.
 type WatchFilter interface {
-	filter(e fsnotify.Event) (bool, error)
+	WatchOption
+	filter(event WatchEvent) (bool, error)
+}
+
+// metadataWatchFilter is a WatchFilter that judges the FileInfo of an event. Events for paths that no longer exist
+// are judged on their last known metadata, and are not accepted when there is none.
+type metadataWatchFilter interface {
+	WatchFilter
+	metadata()
 }
 
 // RegexWatchFilter filters fs events by matching file names to a given regex.
@@ -130,8 +144,12 @@ func NewRegexWatchFilter(filterRegex *regexp.Regexp) RegexWatchFilter {
 	return RegexWatchFilter{regex: filterRegex}
 }
 
-func (rf RegexWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	return rf.regex.MatchString(event.Name), nil
+func (rf RegexWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, rf)
+}
+
+func (rf RegexWatchFilter) filter(event WatchEvent) (bool, error) {
+	return rf.regex.MatchString(event.AbsolutePath), nil
 }
 
 // DateWatchFilter filters fs events by matching ensuring ModTime is within the given date range.
@@ -144,16 +162,14 @@ func NewDateWatchFilter(from, to time.Time) DateWatchFilter {
 	return DateWatchFilter{from: from, to: to}
 }
 
-func (df DateWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	var entry, err = NewEntry(event.Name, 0)
-	if err != nil {
-		return false, err
-	}
+func (df DateWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, df)
+}
 
-	if entry.FileInfo.ModTime().Before(df.from) || entry.FileInfo.ModTime().After(df.to) {
-		return false, nil
-	}
-	return true, nil
+func (df DateWatchFilter) metadata() {}
+
+func (df DateWatchFilter) filter(event WatchEvent) (bool, error) {
+	return !event.FileInfo.ModTime().Before(df.from) && !event.FileInfo.ModTime().After(df.to), nil
 }
 
 // SkipMapWatchFilter filters fs events by ensuring the given file is NOT within the given map.
@@ -165,16 +181,13 @@ func NewSkipMapWatchFilter(skipMap map[string]struct{}) SkipMapWatchFilter {
 	return SkipMapWatchFilter{skipMap: skipMap}
 }
 
-func (smf SkipMapWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	var entry, err = NewEntry(event.Name, 0)
-	if err != nil {
-		return false, err
-	}
+func (smf SkipMapWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, smf)
+}
 
-	if _, has := smf.skipMap[entry.AbsolutePath]; has {
-		return false, nil
-	}
-	return true, nil
+func (smf SkipMapWatchFilter) filter(event WatchEvent) (bool, error) {
+	var _, has = smf.skipMap[event.AbsolutePath]
+	return !has, nil
 }
 
 // PermissionsWatchFilter filters fs events by ensuring the given file permissions are within the given range.
@@ -187,16 +200,14 @@ func NewPermissionsWatchFilter(minimum, maximum uint32) PermissionsWatchFilter {
 	return PermissionsWatchFilter{min: minimum, max: maximum}
 }
 
-func (pf PermissionsWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	var entry, err = NewEntry(event.Name, 0)
-	if err != nil {
-		return false, err
-	}
+func (pf PermissionsWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, pf)
+}
 
-	if entry.FileInfo.Mode() < fs.FileMode(pf.min) || entry.FileInfo.Mode() > fs.FileMode(pf.max) {
-		return false, nil
-	}
-	return true, nil
+func (pf PermissionsWatchFilter) metadata() {}
+
+func (pf PermissionsWatchFilter) filter(event WatchEvent) (bool, error) {
+	return event.FileInfo.Mode() >= fs.FileMode(pf.min) && event.FileInfo.Mode() <= fs.FileMode(pf.max), nil
 }
 
 // SizeWatchFilter filters fs events by ensuring the given file within the given size range (in bytes).
@@ -210,34 +221,66 @@ func NewSizeWatchFilter(minimum, maximum int64) SizeWatchFilter {
 	return SizeWatchFilter{min: minimum, max: maximum}
 }
 
-func (pf SizeWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	var entry, err = NewEntry(event.Name, 0)
-	if err != nil {
-		return false, err
-	}
+func (pf SizeWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, pf)
+}
 
-	if entry.FileInfo.IsDir() {
-		return true, nil
-	} else if entry.FileInfo.Size() < pf.min || entry.FileInfo.Size() > pf.max {
-		return false, nil
-	}
-	return true, nil
+func (pf SizeWatchFilter) metadata() {}
+
+func (pf SizeWatchFilter) filter(event WatchEvent) (bool, error) {
+	return event.FileInfo.IsDir() || (event.FileInfo.Size() >= pf.min && event.FileInfo.Size() <= pf.max), nil
 }
 
-// OpWatchFilter filters fs events by fsnotify.Op event type.
+// OpWatchFilter filters fs events by fsnotify.Op event type. An Op is a bitmask and events often carry more than
+// one, e.g. Create|Write after WithDebounce. An event is accepted when it has any of Ops, or Ops is empty, and has
+// an Op that is not in Exclude.
 type OpWatchFilter struct {
-	Ops []fsnotify.Op
+	Ops     []fsnotify.Op
+	Exclude fsnotify.Op
 }
 
+// NewOpWatchFilter accepts events that have any of ops.
 func NewOpWatchFilter(ops ...fsnotify.Op) OpWatchFilter {
 	return OpWatchFilter{Ops: ops}
 }
 
+// NewExcludeOpWatchFilter drops events that only have ops, e.g. NewExcludeOpWatchFilter(fsnotify.Chmod) drops
+// permission changes but not Write|Chmod.
+func NewExcludeOpWatchFilter(ops ...fsnotify.Op) OpWatchFilter {
+	return OpWatchFilter{}.Excluding(ops...)
+}
+
+// Excluding returns a copy of of that also drops events that only have ops.
+func (of OpWatchFilter) Excluding(ops ...fsnotify.Op) OpWatchFilter {
+	for _, op := range ops {
+		of.Exclude |= op
+	}
+	return of
+}
+
+// apply adds the filter, and keeps fsnotify.Chmod events when they are asked for, see WithChmodEvents.
+func (of OpWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, of)
+	for _, op := range of.Ops {
+		if op.Has(fsnotify.Chmod) {
+			c.chmodEvents = true
+		}
+	}
+}
+
 // nolint: unparam
-func (of OpWatchFilter) filter(event fsnotify.Event) (bool, error) {
-	if slices.Contains(of.Ops, event.Op) {
+func (of OpWatchFilter) filter(event WatchEvent) (bool, error) {
+	if event.Op&^of.Exclude == 0 {
+		return false, nil
+	} else if len(of.Ops) == 0 {
 		return true, nil
 	}
+
+	for _, op := range of.Ops {
+		if event.Has(op) {
+			return true, nil
+		}
+	}
 	return false, nil
 }
 
@@ -248,9 +291,15 @@ func NewDirWatchFilter() DirWatchFilter {
 	return DirWatchFilter{}
 }
 
+func (df DirWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, df)
+}
+
+func (df DirWatchFilter) metadata() {}
+
 // nolint: unparam
-func (df DirWatchFilter) filter(entry Entry) (bool, error) {
-	if entry.FileInfo.IsDir() {
+func (df DirWatchFilter) filter(event WatchEvent) (bool, error) {
+	if event.FileInfo.IsDir() {
 		return true, nil
 	}
 	return false, nil
@@ -263,9 +312,15 @@ func NewFileWatchFilter() FileWatchFilter {
 	return FileWatchFilter{}
 }
 
+func (ff FileWatchFilter) apply(c *watchConfig) {
+	c.filters = append(c.filters, ff)
+}
+
+func (ff FileWatchFilter) metadata() {}
+
 // nolint: unparam
-func (ff FileWatchFilter) filter(entry Entry) (bool, error) {
-	if entry.FileInfo.IsDir() {
+func (ff FileWatchFilter) filter(event WatchEvent) (bool, error) {
+	if event.FileInfo.IsDir() {
 		return false, nil
 	}
 	return true, nil
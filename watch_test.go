@@ -3,6 +3,7 @@ package path
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -121,6 +122,43 @@ func TestWatchDirRecursive(t *testing.T) {
 	assert.NoError(t, os.RemoveAll(dir))
 }
 
+func TestWatchDirStartupCancelled(t *testing.T) {
+	t.Parallel()
+
+	// nobody reads errors once ctx is done, the error of a failed start is dropped then
+	var ctx, cancel = context.WithCancel(t.Context())
+	cancel()
+
+	var returned = make(chan struct{})
+	go func() {
+		WatchDirOptions(ctx, "./testdata/notexist", 0, false, make(chan WatchEvent), make(chan error))
+		WatchFile(ctx, "./testdata/notexist/file.txt", make(chan WatchEvent), make(chan error))
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "blocked sending the start up error")
+	}
+}
+
+func TestWatchEventString(t *testing.T) {
+	t.Parallel()
+
+	var event = WatchEvent{Entry: Entry{AbsolutePath: "/tmp/file.txt"}, Op: fsnotify.Create | fsnotify.Write}
+	assert.Equal(t, "CREATE|WRITE", event.OpString())
+	assert.Equal(t, "CREATE|WRITE /tmp/file.txt", event.String())
+	assert.Equal(t, "CREATE|WRITE /tmp/file.txt", fmt.Sprint(event))
+
+	event.Op = Modified
+	assert.Equal(t, "MODIFIED /tmp/file.txt", event.String())
+	event.Op = fsnotify.Create | fsnotify.Write | Ready
+	assert.Equal(t, "CREATE|WRITE|READY", event.OpString())
+	event.Op = 0
+	assert.Equal(t, "[no events]", event.OpString())
+}
+
 func TestSkipMapWatchFilter(t *testing.T) {
 	t.Parallel()
 
@@ -128,20 +166,16 @@ func TestSkipMapWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var skipMapFilter = NewSkipMapWatchFilter(map[string]struct{}{testFile.AbsolutePath: {}})
-	accpet, err := skipMapFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err := skipMapFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 
 	testFileTwo, err := NewEntry("./testdata/one/file.mp3", 0)
 	assert.NoError(t, err)
 
-	accpet, err = skipMapFilter.filter(fsnotify.Event{Name: testFileTwo.AbsolutePath})
+	accpet, err = skipMapFilter.filter(WatchEvent{Entry: testFileTwo})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
-
-	accpet, err = skipMapFilter.filter(fsnotify.Event{Name: "filenotexists"})
-	assert.Error(t, err)
-	assert.False(t, accpet)
 }
 
 func TestDateWatchFilter(t *testing.T) {
@@ -157,20 +191,16 @@ func TestDateWatchFilter(t *testing.T) {
 
 	var fromTime = time.Date(2022, 07, 01, 0, 0, 0, 0, time.UTC)
 	var dateFilter = NewDateWatchFilter(fromTime, time.Now())
-	accpet, err := dateFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err := dateFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 
 	testFile, err = NewEntry("./testdata/one/file.mp3", 0)
 	assert.NoError(t, err)
 
-	accpet, err = dateFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err = dateFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
-
-	accpet, err = dateFilter.filter(fsnotify.Event{Name: "filenotexists"})
-	assert.Error(t, err)
-	assert.False(t, accpet)
 }
 
 func TestPermissionsWatchFilter(t *testing.T) {
@@ -183,7 +213,7 @@ func TestPermissionsWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var permsFilter = NewPermissionsWatchFilter(uint32(fs.ModePerm), uint32(fs.ModePerm))
-	accpet, err := permsFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err := permsFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	if runtime.GOOS != "windows" { // i give up trying to figure out how windows does perms
 		assert.True(t, accpet)
@@ -192,7 +222,7 @@ func TestPermissionsWatchFilter(t *testing.T) {
 	testFile, err = NewEntry("./testdata/one/file.mp4", 0)
 	assert.NoError(t, err)
 
-	accpet, err = permsFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err = permsFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 }
@@ -204,27 +234,23 @@ func TestSizeWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var sizeFilter = NewSizeWatchFilter(4000, 6000)
-	accpet, err := sizeFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err := sizeFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
 
 	testFile, err = NewEntry("./testdata/one/file.mp3", 0)
 	assert.NoError(t, err)
 
-	accpet, err = sizeFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err = sizeFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 
 	testFile, err = NewEntry("./testdata/one", 0)
 	assert.NoError(t, err)
 
-	accpet, err = sizeFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath})
+	accpet, err = sizeFilter.filter(WatchEvent{Entry: testFile})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
-
-	accpet, err = sizeFilter.filter(fsnotify.Event{Name: "filenotexists"})
-	assert.Error(t, err)
-	assert.False(t, accpet)
 }
 
 func TestOpWatchFilter(t *testing.T) {
@@ -234,13 +260,64 @@ func TestOpWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var opFilter = NewOpWatchFilter(fsnotify.Create)
-	accpet, err := opFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath, Op: fsnotify.Create})
+	accpet, err := opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Create})
+	assert.NoError(t, err)
+	assert.True(t, accpet)
+
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Remove})
+	assert.NoError(t, err)
+	assert.False(t, accpet)
+
+	// ops are bitmasks
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Create | fsnotify.Write})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
 
-	accpet, err = opFilter.filter(fsnotify.Event{Name: testFile.AbsolutePath, Op: fsnotify.Remove})
+	opFilter = NewExcludeOpWatchFilter(fsnotify.Chmod)
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Chmod})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
+
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Write | fsnotify.Chmod})
+	assert.NoError(t, err)
+	assert.True(t, accpet)
+
+	opFilter = NewOpWatchFilter(fsnotify.Create, fsnotify.Write).Excluding(fsnotify.Chmod, fsnotify.Write)
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Write | fsnotify.Chmod})
+	assert.NoError(t, err)
+	assert.False(t, accpet)
+
+	accpet, err = opFilter.filter(WatchEvent{Entry: testFile, Op: fsnotify.Create | fsnotify.Write})
+	assert.NoError(t, err)
+	assert.True(t, accpet)
+}
+
+func TestWatchDirChmod(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("windows does not report permission changes")
+	}
+
+	for _, opts := range [][]WatchOption{{}, {WithChmodEvents()}, {NewOpWatchFilter(fsnotify.Chmod)}} {
+		var dir = t.TempDir()
+		var file = filepath.Join(dir, "file.txt")
+		assert.NoError(t, os.WriteFile(file, []byte{}, 0o600))
+
+		var watcher, err = NewWatcher(t.Context(), 0, opts...)
+		assert.NoError(t, err)
+		assert.NoError(t, watcher.Add(dir))
+
+		// permission changes are dropped by default, the write after it is not
+		assert.NoError(t, os.Chmod(file, 0o644))
+		time.Sleep(time.Millisecond * 50)
+		assert.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+
+		var event = <-watcher.Events()
+		assert.Equal(t, file, event.AbsolutePath)
+		assert.Equal(t, len(opts) > 0, event.Op == fsnotify.Chmod, event.Op)
+		assert.NoError(t, watcher.Close())
+	}
 }
 
 func TestDirWatchFilter(t *testing.T) {
@@ -250,14 +327,14 @@ func TestDirWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var dirFilter = NewDirWatchFilter()
-	accpet, err := dirFilter.filter(entry)
+	accpet, err := dirFilter.filter(WatchEvent{Entry: entry})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
 
 	entry, err = NewEntry("./testdata/ogCGs91VSA5FBjJdgE8eeLSngbebPXyDCICZ7I~tplv-f5insbecw7-1 720 720.jpg", 1)
 	assert.NoError(t, err)
 
-	accpet, err = dirFilter.filter(entry)
+	accpet, err = dirFilter.filter(WatchEvent{Entry: entry})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 }
@@ -269,14 +346,125 @@ func TestFileWatchFilter(t *testing.T) {
 	assert.NoError(t, err)
 
 	var dirFilter = NewFileWatchFilter()
-	accpet, err := dirFilter.filter(entry)
+	accpet, err := dirFilter.filter(WatchEvent{Entry: entry})
 	assert.NoError(t, err)
 	assert.False(t, accpet)
 
 	entry, err = NewEntry("./testdata/ogCGs91VSA5FBjJdgE8eeLSngbebPXyDCICZ7I~tplv-f5insbecw7-1 720 720.jpg", 1)
 	assert.NoError(t, err)
 
-	accpet, err = dirFilter.filter(entry)
+	accpet, err = dirFilter.filter(WatchEvent{Entry: entry})
 	assert.NoError(t, err)
 	assert.True(t, accpet)
 }
+
+func TestWatchDirRemoveMetadata(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), make([]byte, 100), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "small.txt"), make([]byte, 1), os.ModePerm))
+
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDir(ctx, dir, 0, false, files, errs, NewSizeWatchFilter(50, 200), NewOpWatchFilter(fsnotify.Remove))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	// gone files are judged and described by what they were
+	assert.NoError(t, os.Remove(filepath.Join(dir, "small.txt")))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "big.txt")))
+
+	var event = <-files
+	assert.Equal(t, fsnotify.Remove, event.Op)
+	assert.Equal(t, filepath.Join(dir, "big.txt"), event.AbsolutePath)
+	assert.Equal(t, int64(100), event.FileInfo.Size())
+
+	select {
+	case event := <-files:
+		assert.Fail(t, "unexpected event", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	cancel()
+}
+
+func TestWatchFilterOrder(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, os.ModePerm))
+
+	var files = make(chan WatchEvent, 1)
+	var errs = make(chan error, 1)
+	var config = newWatchConfig(NewRegexWatchFilter(regexp.MustCompile(`\.txt$`)), NewDateWatchFilter(time.Time{}, time.Now().Add(time.Hour)))
+	var dw = newDirWatcher(nil, 0, files, errs, make(chan struct{}), config)
+
+	// paths the path filters reject are never stat'd
+	dw.handle(trackedEvent{Event: fsnotify.Event{Name: filepath.Join(dir, "missing.log"), Op: fsnotify.Write}})
+	assert.Empty(t, errs)
+	assert.Empty(t, files)
+
+	dw.handle(trackedEvent{Event: fsnotify.Event{Name: file, Op: fsnotify.Write}})
+	var event = <-files
+	assert.Equal(t, file, event.AbsolutePath)
+	assert.False(t, event.IsDir())
+	assert.Empty(t, event.Children)
+
+	// a removed path without known metadata is not accepted by filters on metadata
+	dw.handle(trackedEvent{Event: fsnotify.Event{Name: filepath.Join(dir, "gone.txt"), Op: fsnotify.Remove}})
+	assert.Empty(t, files)
+
+	// without them it is published by name
+	dw = newDirWatcher(nil, 0, files, errs, make(chan struct{}), newWatchConfig())
+	dw.handle(trackedEvent{Event: fsnotify.Event{Name: filepath.Join(dir, "gone.txt"), Op: fsnotify.Remove}})
+	event = <-files
+	assert.Equal(t, "gone.txt", event.FileInfo.Name())
+	assert.Empty(t, errs)
+}
+
+// BenchmarkWatchFilters compares building an Entry for every filter and again for the published event, as the
+// pipeline used to, to handling the event as the pipeline does now, building it once and sharing it.
+func BenchmarkWatchFilters(b *testing.B) {
+	var filters = []WatchFilter{
+		NewDateWatchFilter(time.Time{}, time.Now().Add(time.Hour)),
+		NewSkipMapWatchFilter(map[string]struct{}{}),
+		NewPermissionsWatchFilter(0, uint32(fs.ModePerm)),
+		NewSizeWatchFilter(0, 1<<20),
+	}
+	var name, err = filepath.Abs("./testdata/one/file.mp4")
+	assert.NoError(b, err)
+
+	b.Run("entry per filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for _, fn := range filters {
+				var entry, err = NewEntry(name, 0)
+				assert.NoError(b, err)
+				var _, _ = fn.filter(WatchEvent{Entry: entry, Op: fsnotify.Write})
+			}
+			var _, err = NewEntry(name, 0)
+			assert.NoError(b, err)
+		}
+	})
+
+	b.Run("shared entry", func(b *testing.B) {
+		var files = make(chan WatchEvent, 1)
+		var dw = newDirWatcher(nil, 0, files, make(chan error), make(chan struct{}), newWatchConfig(WithFilters(filters...)))
+		var event = trackedEvent{Event: fsnotify.Event{Name: name, Op: fsnotify.Write}}
+
+		b.ReportAllocs()
+		for b.Loop() {
+			dw.handle(event)
+			<-files
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package path
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrWatchLimit is returned when the OS does not allow any more watches. On Linux the limit is the
+// fs.inotify.max_user_watches sysctl, raise it or leave out directories that do not need watching with WithPrune
+// or WithIgnoreFile.
+var ErrWatchLimit = errors.New("watch limit reached, see fs.inotify.max_user_watches")
+
+// watchedDirs tracks the directories registered with the watch backend so the set can follow directories that are
+// created and removed while watching. Directories are only watched up to maxDepth levels below one of the roots.
+type watchedDirs struct {
+	mu       sync.Mutex
+	watcher  watchBackend
+	roots    map[string]struct{}
+	maxDepth uint8
+	dirs     map[string]struct{}
+	prune    *pruner // paths that are never watched nor reported, nil for none
+}
+
+func newWatchedDirs(watcher watchBackend, maxDepth uint8, roots ...string) *watchedDirs {
+	var wd = &watchedDirs{watcher: watcher, roots: make(map[string]struct{}), maxDepth: maxDepth, dirs: make(map[string]struct{})}
+	for _, root := range roots {
+		wd.roots[root] = struct{}{}
+	}
+	return wd
+}
+
+// depth returns how many levels below the closest root dir is, or -1 if it is not within any root.
+func (wd *watchedDirs) depth(dir string) int {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	return wd.depthLocked(dir)
+}
+
+func (wd *watchedDirs) depthLocked(dir string) int {
+	var _, depth = wd.rootLocked(dir)
+	return depth
+}
+
+// rootLocked returns the closest root of dir and how many levels below it dir is, or -1 if it is not within any root.
+func (wd *watchedDirs) rootLocked(dir string) (string, int) {
+	var closest, closestRoot = -1, ""
+	for root := range wd.roots {
+		var rel, err = filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		var depth = 0
+		if rel != "." {
+			depth = strings.Count(rel, string(filepath.Separator)) + 1
+		}
+		if closest < 0 || depth < closest {
+			closest, closestRoot = depth, root
+		}
+	}
+	return closestRoot, closest
+}
+
+// pruned reports if path is excluded by a prune rule of its root. isDir is only called when it matters.
+func (wd *watchedDirs) pruned(path string, isDir func() bool) bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	var root, depth = wd.rootLocked(path)
+	return depth > 0 && wd.prune.pruned(root, path, isDir)
+}
+
+// addRoot makes root a root and adds it, see add.
+func (wd *watchedDirs) addRoot(root string) ([]Entry, error) {
+	wd.mu.Lock()
+	wd.roots[root] = struct{}{}
+	var err error
+	if wd.prune != nil {
+		err = wd.prune.load(root)
+	}
+	wd.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return wd.add(root)
+}
+
+// removeRoot drops root and every watched directory that is no longer within another root.
+// It reports false if root was not a root.
+func (wd *watchedDirs) removeRoot(root string) bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	if _, has := wd.roots[root]; !has {
+		return false
+	}
+	delete(wd.roots, root)
+
+	for p := range wd.dirs {
+		if depth := wd.depthLocked(p); depth < 0 || depth > int(wd.maxDepth) {
+			unwatch(wd.watcher, p)
+			delete(wd.dirs, p)
+		}
+	}
+	return true
+}
+
+// isRoot reports if path is a root.
+func (wd *watchedDirs) isRoot(path string) bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	var _, has = wd.roots[path]
+	return has
+}
+
+// rootList returns the roots in order.
+func (wd *watchedDirs) rootList() []string {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	var roots = make([]string, 0, len(wd.roots))
+	for root := range wd.roots {
+		roots = append(roots, root)
+	}
+	slices.Sort(roots)
+	return roots
+}
+
+// add registers dir and every directory below it that is within maxDepth. It returns the entries found inside dir
+// so the caller can report anything that was created before the watch was in place.
+func (wd *watchedDirs) add(dir string) ([]Entry, error) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	var dirDepth = wd.depthLocked(dir)
+	if dirDepth < 0 || dirDepth > int(wd.maxDepth) {
+		return nil, nil
+	}
+
+	var found []Entry
+	var err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) { // removed while we were walking
+				return nil
+			}
+			return err
+		}
+
+		if p != dir {
+			if root, _ := wd.rootLocked(p); wd.prune.pruned(root, p, d.IsDir) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			var info, err = d.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			found = append(found, Entry{AbsolutePath: p, FileInfo: info})
+		}
+
+		if d.IsDir() && wd.depthLocked(p) > int(wd.maxDepth) {
+			return filepath.SkipDir
+		} else if !d.IsDir() && p != dir { // only a file given as dir itself is watched
+			return nil
+		}
+
+		if _, has := wd.dirs[p]; !has {
+			if err := wd.watcher.Add(p); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir
+				} else if isWatchLimit(err) {
+					return fmt.Errorf("error adding path to watcher: %s: %w: %w", p, ErrWatchLimit, err)
+				}
+				return fmt.Errorf("error adding path to watcher: %w", err)
+			}
+			wd.dirs[p] = struct{}{}
+		}
+		return nil
+	})
+
+	return found, err
+}
+
+// remove drops dir and every watched directory below it.
+func (wd *watchedDirs) remove(dir string) {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	for p := range wd.dirs {
+		if p == dir || strings.HasPrefix(p, dir+string(filepath.Separator)) {
+			unwatch(wd.watcher, p)
+			delete(wd.dirs, p)
+		}
+	}
+}
+
+// has reports if dir is being watched.
+func (wd *watchedDirs) has(dir string) bool {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+
+	var _, has = wd.dirs[dir]
+	return has
+}
@@ -0,0 +1,130 @@
+package path
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchedDirsDepth(t *testing.T) {
+	t.Parallel()
+
+	var root = filepath.Join(t.TempDir(), "root")
+	var wd = newWatchedDirs(nil, 2, root)
+
+	assert.Equal(t, 0, wd.depth(root))
+	assert.Equal(t, 1, wd.depth(filepath.Join(root, "a")))
+	assert.Equal(t, 3, wd.depth(filepath.Join(root, "a", "b", "c")))
+	assert.Equal(t, -1, wd.depth(filepath.Dir(root)))
+	assert.Equal(t, -1, wd.depth(root+"2"))
+
+	// the closest root wins
+	wd = newWatchedDirs(nil, 2, root, filepath.Join(root, "a", "b"))
+	assert.Equal(t, 1, wd.depth(filepath.Join(root, "a", "b", "c")))
+	assert.Equal(t, 1, wd.depth(filepath.Join(root, "a")))
+}
+
+func TestWatchedDirsAddRemove(t *testing.T) {
+	t.Parallel()
+
+	var root = t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "a", "b", "c"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "a", "file.txt"), []byte{}, os.ModePerm))
+
+	var watcher, err = fsnotify.NewWatcher()
+	assert.NoError(t, err)
+	defer watcher.Close()
+
+	var wd = newWatchedDirs(fsnotifyBackend{Watcher: watcher}, 2)
+	found, err := wd.addRoot(root)
+	assert.NoError(t, err)
+	assert.Len(t, found, 4) // a, a/b, a/file.txt, a/b/c
+	assert.Len(t, watcher.WatchList(), 3)
+	assert.True(t, wd.has(filepath.Join(root, "a", "b")))
+	assert.False(t, wd.has(filepath.Join(root, "a", "b", "c"))) // too deep
+
+	wd.remove(filepath.Join(root, "a"))
+	assert.Len(t, watcher.WatchList(), 1)
+	assert.True(t, wd.has(root))
+
+	// a second root brings c within reach, removing it only drops what the first root does not cover
+	_, err = wd.add(filepath.Join(root, "a"))
+	assert.NoError(t, err)
+	_, err = wd.addRoot(filepath.Join(root, "a", "b"))
+	assert.NoError(t, err)
+	assert.True(t, wd.has(filepath.Join(root, "a", "b", "c")))
+	assert.Equal(t, []string{root, filepath.Join(root, "a", "b")}, wd.rootList())
+
+	assert.True(t, wd.removeRoot(filepath.Join(root, "a", "b")))
+	assert.False(t, wd.removeRoot(filepath.Join(root, "a", "b")))
+	assert.False(t, wd.has(filepath.Join(root, "a", "b", "c")))
+	assert.True(t, wd.has(filepath.Join(root, "a", "b")))
+	assert.Len(t, watcher.WatchList(), 3)
+}
+
+func TestWatchDirFollowsNewDirs(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+	var regexFilter = NewRegexWatchFilter(regexp.MustCompile(".*.txt$"))
+
+	var mu sync.Mutex
+	var seen = make(map[string]struct{})
+	var done = make(chan struct{})
+	go func() {
+		for file := range files {
+			mu.Lock()
+			seen[strings.TrimPrefix(file.AbsolutePath, dir)] = struct{}{}
+			mu.Unlock()
+		}
+		close(done)
+	}()
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+
+	go WatchDirOptions(ctx, dir, 1, false, files, errs, regexFilter, NewOpWatchFilter(fsnotify.Create, fsnotify.Write), WithWatchDepth(3))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	// a new directory is watched once created
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "new"), os.ModePerm))
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "new", "file1.txt"), []byte{}, os.ModePerm))
+
+	// a whole tree created at once, the file may be in place before its directory is watched
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "tree", "a"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "tree", "a", "file2.txt"), []byte{}, os.ModePerm))
+
+	// too deep to be watched
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "tree", "a", "b", "c"), os.ModePerm))
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "tree", "a", "b", "c", "file3.txt"), []byte{}, os.ModePerm))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to process events
+
+	// removed directories are dropped
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "tree")))
+	time.Sleep(time.Millisecond * 250)
+
+	cancel()
+	<-done
+
+	var sep = string(filepath.Separator)
+	assert.Contains(t, seen, sep+filepath.Join("new", "file1.txt"))
+	assert.Contains(t, seen, sep+filepath.Join("tree", "a", "file2.txt"))
+	assert.NotContains(t, seen, sep+filepath.Join("tree", "a", "b", "c", "file3.txt"))
+}
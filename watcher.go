@@ -0,0 +1,199 @@
+package path
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches any number of paths for changes. Events and errors are published on the channels returned by
+// Events and Errors, the Watcher owns both and closes them once it has stopped, either because Close was called
+// or the ctx given to NewWatcher is done. Both channels have to be read until then, or the watch stalls.
+// No goroutines are left running after the channels are closed.
+type Watcher struct {
+	dw     *dirWatcher
+	events chan WatchEvent
+	errors chan error
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex // guards paths and serializes Add and Remove
+	paths  map[string]struct{}
+	err    error // from saving the state file when the watch stopped
+}
+
+// NewWatcher starts a Watcher that watches every added path and, with a recursiveDepth > 0, every directory below it,
+// see WithWatchDepth. Filters and other WatchOptions such as WithDebounce are given in opts.
+func NewWatcher(ctx context.Context, recursiveDepth uint8, opts ...WatchOption) (*Watcher, error) {
+	var config = newWatchConfig(opts...)
+
+	var prune, err = newPruner(config.prune, config.ignoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	backend, err := newWatchBackend(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var w = &Watcher{
+		events: make(chan WatchEvent),
+		errors: make(chan error),
+		cancel: cancel,
+		done:   make(chan struct{}),
+		paths:  make(map[string]struct{}),
+	}
+	w.dw = newDirWatcher(backend, recursiveDepth, w.events, w.errors, ctx.Done(), config)
+	w.dw.dirs.prune = prune
+
+	if config.stateFile != "" {
+		if w.dw.saved, err = loadState(config.stateFile); err != nil {
+			cancel()
+			_ = backend.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		defer close(w.done)
+		defer close(w.errors)
+		defer close(w.events)
+
+		w.dw.run()
+		cancel()
+		// the watch is stopped, there is nobody to report a failed close to
+		_ = backend.Close()
+
+		if config.stateFile != "" {
+			w.err = w.dw.saveState()
+		}
+	}()
+
+	return w, nil
+}
+
+// Events returns the channel changes are published on.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Errors returns the channel errors are published on.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Add starts watching inputPath, it must exist unless WithMissingPaths is used. Changes to anything already in it
+// are published from now on.
+// inputPath may be a glob pattern such as /data/*/incoming, every match is watched and the pattern is evaluated
+// again whenever something is created in a directory that could lead to a new match. Only the directory above
+// the first glob meta character has to exist.
+func (w *Watcher) Add(inputPath string) error {
+	inputPath = filepath.Clean(strings.TrimSpace(inputPath))
+
+	var path string
+	if isGlob(inputPath) {
+		var abs, err = absolutePath(inputPath)
+		if err != nil {
+			return fmt.Errorf("error with inputPath: %w", err)
+		}
+		if _, err := filepath.Match(abs, ""); err != nil {
+			return fmt.Errorf("error with inputPath: %s, error: %w", inputPath, err)
+		}
+		var base, _ = splitGlob(abs)
+		if info, err := os.Stat(base); err != nil {
+			return fmt.Errorf("error with inputPath: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("error with inputPath: %s, %s is not a directory", inputPath, base)
+		}
+		path = abs
+	} else if w.dw.config.missingPaths {
+		var abs, err = absolutePath(inputPath)
+		if err != nil {
+			return fmt.Errorf("error with inputPath: %w", err)
+		}
+		path = abs
+	} else {
+		var entry, err = NewEntry(inputPath, 0)
+		if err != nil {
+			return fmt.Errorf("error with inputPath: %w", err)
+		}
+		path = entry.AbsolutePath
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed() {
+		return fsnotify.ErrClosed
+	}
+	if err := w.dw.addPath(path); err != nil {
+		return err
+	}
+	w.paths[path] = struct{}{}
+	return nil
+}
+
+// Remove stops watching inputPath, it must have been given to Add.
+func (w *Watcher) Remove(inputPath string) error {
+	var abs, err = absolutePath(filepath.Clean(strings.TrimSpace(inputPath)))
+	if err != nil {
+		return fmt.Errorf("error with inputPath: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed() {
+		return fsnotify.ErrClosed
+	}
+	if err := w.dw.removePath(abs); err != nil {
+		return err
+	}
+	delete(w.paths, abs)
+	return nil
+}
+
+// WatchedPaths returns the absolute paths and glob patterns given to Add that are still being watched, in order.
+func (w *Watcher) WatchedPaths() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var paths = make([]string, 0, len(w.paths))
+	for path := range w.paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// Stats returns how often events were dropped by the OS and how many were recovered. Each time the OS event queue
+// overflows every path is rescanned and the differences are published as creates, writes and removes, the
+// overflow is not reported on Errors.
+func (w *Watcher) Stats() WatchStats {
+	return WatchStats{Overflows: w.dw.counters.overflows.Load(), Recovered: w.dw.counters.recovered.Load()}
+}
+
+// Close stops the watch and waits for the Events and Errors channels to be closed. Events that are being held,
+// e.g. by WithDebounce, are dropped. The error is from saving the WithStateFile state, if any.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.done
+	return w.err
+}
+
+// closed reports if the watch has stopped.
+func (w *Watcher) closed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,163 @@
+package path
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Parallel()
+
+	var one, two = t.TempDir(), t.TempDir()
+	var watcher, err = NewWatcher(t.Context(), 0)
+	assert.NoError(t, err)
+
+	assert.NoError(t, watcher.Add(one))
+	assert.NoError(t, watcher.Add(two))
+	assert.ErrorIs(t, watcher.Add(filepath.Join(one, "missing")), os.ErrNotExist)
+	assert.ElementsMatch(t, []string{one, two}, watcher.WatchedPaths())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(two, "file.txt"), []byte{}, os.ModePerm))
+	var event = <-watcher.Events()
+	assert.Equal(t, filepath.Join(two, "file.txt"), event.AbsolutePath)
+	assert.True(t, event.Has(fsnotify.Create))
+
+	// nothing more is heard from a removed path
+	assert.NoError(t, watcher.Remove(two))
+	assert.ErrorIs(t, watcher.Remove(two), fsnotify.ErrNonExistentWatch)
+	assert.Equal(t, []string{one}, watcher.WatchedPaths())
+	assert.NoError(t, os.WriteFile(filepath.Join(two, "other.txt"), []byte{}, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(one, "file.txt"), []byte{}, os.ModePerm))
+	event = <-watcher.Events()
+	assert.Equal(t, filepath.Join(one, "file.txt"), event.AbsolutePath)
+
+	assert.NoError(t, watcher.Close())
+	assert.NoError(t, watcher.Close())
+	for range watcher.Events() { //nolint:revive // drain anything published before the close
+	}
+	var _, open = <-watcher.Errors()
+	assert.False(t, open)
+	assert.ErrorIs(t, watcher.Add(one), fsnotify.ErrClosed)
+}
+
+func TestWatcherContext(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var ctx, cancel = context.WithCancel(t.Context())
+	var watcher, err = NewWatcher(ctx, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, watcher.Add(dir))
+
+	// an event nobody reads does not keep the watch alive
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte{}, os.ModePerm))
+	time.Sleep(time.Millisecond * 100)
+	cancel()
+
+	select {
+	case <-watcher.done:
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "watcher did not stop")
+	}
+	var _, open = <-watcher.Events()
+	assert.False(t, open)
+	_, open = <-watcher.Errors()
+	assert.False(t, open)
+}
+
+func TestWatchDirInitialEvents(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), os.ModePerm))
+	for i := range 50 {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", fmt.Sprintf("before%d.txt", i)), []byte{}, os.ModePerm))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "skipped.log"), []byte{}, os.ModePerm))
+
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+
+	// files created while the watch starts up are either listed or seen live, never both
+	var created = make(chan struct{})
+	go func() {
+		defer close(created)
+		for i := range 50 {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", fmt.Sprintf("during%d.txt", i)), []byte{}, os.ModePerm))
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 1, false, files, errs, WithInitialEvents(), NewRegexWatchFilter(regexp.MustCompile(`\.txt$`)), NewOpWatchFilter(fsnotify.Create))
+
+	var seen = make(map[string]int)
+	var timeout = time.After(time.Second * 5)
+	for len(seen) < 100 {
+		select {
+		case event := <-files:
+			seen[filepath.Base(event.AbsolutePath)]++
+		case <-timeout:
+			assert.FailNow(t, "missing events", "saw %d of 100", len(seen))
+		}
+	}
+	<-created
+
+	// and anything later is live
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "after.txt"), []byte{}, os.ModePerm))
+	for event := range files {
+		if filepath.Base(event.AbsolutePath) == "after.txt" {
+			break
+		}
+		seen[filepath.Base(event.AbsolutePath)]++
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	cancel()
+	for event := range files {
+		seen[filepath.Base(event.AbsolutePath)]++
+	}
+
+	assert.Len(t, seen, 100)
+	for name, count := range seen {
+		assert.Equal(t, 1, count, name)
+	}
+}
+
+func TestPublishListedForgotten(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte{}, os.ModePerm))
+	var info, err = os.Lstat(file)
+	assert.NoError(t, err)
+
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent, 1)
+	var dw = newDirWatcher(nil, 0, files, make(chan error, 1), make(chan struct{}), newWatchConfig(WithClock(clock)))
+	assert.Nil(t, dw.timer())
+
+	dw.publishListed([]Entry{{AbsolutePath: file, FileInfo: info}})
+	assert.Equal(t, file, (<-files).AbsolutePath)
+	assert.Contains(t, dw.listed, file)
+	assert.NotNil(t, dw.timer())
+
+	// the listing is only matched against the live events that raced with it
+	clock.Advance(listedWindow)
+	dw.flush()
+	assert.Empty(t, dw.listed)
+	assert.Nil(t, dw.timer())
+}
@@ -0,0 +1,90 @@
+package path
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Modified is the Op WatchFile publishes once a file was saved, however it was written. fsnotify.Op.String does not
+// know it, WatchEvent.String and WatchEvent.OpString do.
+const Modified fsnotify.Op = 1 << 30
+
+// fileSaveWindow is how long WatchFile waits for the events of one save to stop by default.
+const fileSaveWindow = time.Millisecond * 100
+
+// WatchFile watches a single file and publishes one WatchEvent on files each time it is saved, with Op Modified.
+// Editors and tools that save by writing a temporary file and renaming it over the file, or by moving the file aside
+// first, replace it with a new one; a watch on the file itself would be lost then. WatchFile watches the directory
+// it is in instead, so the file does not have to exist yet, and waits until the events of one save have stopped for
+// 100ms, see WithDebounce, before publishing. A file that is created is published with fsnotify.Create and one that
+// is removed, and not replaced, with fsnotify.Remove. Changes to only its permissions are not published.
+// WatchFile blocks until ctx is done and then closes files and errors. If the watch can not be started the error is
+// sent on errors, unless ctx is done, and neither channel is closed.
+func WatchFile(ctx context.Context, inputPath string, files chan WatchEvent, errors chan error, opts ...WatchOption) {
+	var file, err = absolutePath(inputPath)
+	if err != nil {
+		select {
+		case errors <- fmt.Errorf("error with inputPath: %w", err):
+		case <-ctx.Done():
+		}
+		return
+	}
+
+	opts = append([]WatchOption{WithDebounce(fileSaveWindow)}, opts...)
+	opts = append(opts, NewRegexWatchFilter(regexp.MustCompile("^"+regexp.QuoteMeta(file)+"$")))
+
+	watcher, err := NewWatcher(ctx, 0, opts...)
+	if err != nil {
+		select {
+		case errors <- err:
+		case <-ctx.Done():
+		}
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		select {
+		case errors <- err:
+		case <-ctx.Done():
+		}
+		return
+	}
+
+	defer close(files)
+	defer close(errors)
+
+	var _, statErr = os.Lstat(file)
+	var exists = statErr == nil
+
+	forward(ctx, watcher, files, errors, func(event WatchEvent) (WatchEvent, bool) {
+		var saved, publish = fileSaved(file, event, exists)
+		if publish {
+			exists = saved.Op != fsnotify.Remove
+		}
+		return saved, publish
+	})
+}
+
+// fileSaved turns the events of one save of file into the WatchEvent WatchFile publishes, existed is if the file
+// was there before. It reports false if there is nothing to publish.
+func fileSaved(file string, event WatchEvent, existed bool) (WatchEvent, bool) {
+	var info, err = os.Lstat(file)
+	switch {
+	case err != nil && existed:
+		return WatchEvent{Entry: Entry{AbsolutePath: file, FileInfo: event.FileInfo}, Op: fsnotify.Remove}, true
+	case err != nil:
+		return WatchEvent{}, false
+	case !existed:
+		return WatchEvent{Entry: Entry{AbsolutePath: file, FileInfo: info}, Op: fsnotify.Create}, true
+	case event.Op == fsnotify.Chmod:
+		return WatchEvent{}, false
+	}
+	return WatchEvent{Entry: Entry{AbsolutePath: file, FileInfo: info}, Op: Modified}, true
+}
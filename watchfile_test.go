@@ -0,0 +1,82 @@
+package path
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchFile(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "config.yaml")
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchFile(t.Context(), file, files, errs, WithDebounce(time.Millisecond*50))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchFile to start up
+
+	// next returns the next event and makes sure no other follows it for the same save
+	var next = func() WatchEvent {
+		var event WatchEvent
+		select {
+		case event = <-files:
+		case <-time.After(time.Second * 5):
+			assert.FailNow(t, "missing event")
+		}
+		select {
+		case extra := <-files:
+			assert.Fail(t, "more than one event", "%s %s", extra.Op, extra.AbsolutePath)
+		case <-time.After(time.Millisecond * 200):
+		}
+		return event
+	}
+
+	// the file does not have to exist yet
+	assert.NoError(t, os.WriteFile(file, []byte("one"), 0o600))
+	var event = next()
+	assert.Equal(t, fsnotify.Create, event.Op)
+	assert.Equal(t, file, event.AbsolutePath)
+
+	// written in place
+	assert.NoError(t, os.WriteFile(file, []byte("two"), 0o600))
+	event = next()
+	assert.Equal(t, Modified, event.Op)
+	assert.Equal(t, int64(3), event.FileInfo.Size())
+
+	// written to a temporary file that is renamed over it
+	var tmp = filepath.Join(dir, ".config.yaml.tmp")
+	assert.NoError(t, os.WriteFile(tmp, []byte("three"), 0o600))
+	assert.NoError(t, os.Rename(tmp, file))
+	event = next()
+	assert.Equal(t, Modified, event.Op)
+	assert.Equal(t, int64(5), event.FileInfo.Size())
+
+	// moved aside, written again and the backup removed, as vim does
+	var backup = file + "~"
+	assert.NoError(t, os.Rename(file, backup))
+	assert.NoError(t, os.WriteFile(file, []byte("four!"), 0o600))
+	assert.NoError(t, os.Remove(backup))
+	event = next()
+	assert.Equal(t, Modified, event.Op)
+
+	// other files in the directory, and permissions, are not reported
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte{}, 0o600))
+	assert.NoError(t, os.Chmod(file, 0o644))
+
+	assert.NoError(t, os.Remove(file))
+	event = next()
+	assert.Equal(t, fsnotify.Remove, event.Op)
+	assert.Equal(t, file, event.AbsolutePath)
+}
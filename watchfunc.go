@@ -0,0 +1,59 @@
+package path
+
+import (
+	"context"
+)
+
+// WatchFunc watches inputPath like WatchDir and calls handler for every WatchEvent. Handlers run on a Dispatcher,
+// events for the same path are handled one at a time and in order while events for different paths are handled in
+// parallel. The Dispatcher is configured by the same opts, e.g. WithWorkers and WithRetry.
+// WatchFunc blocks until ctx is done and returns nil, or until a handler still fails after its retries and returns
+// that error. Either way it waits for running handlers to return first. Errors of the watch itself are given to the
+// func set with WithErrorHandler.
+func WatchFunc(ctx context.Context, inputPath string, recursiveDepth uint8, handler func(WatchEvent) error, opts ...WatchOption) error {
+	var config = newWatchConfig(opts...)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var watcher, err = NewWatcher(ctx, recursiveDepth, opts...)
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(inputPath); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+
+	var dispatcher = NewDispatcher(ctx, handler, opts...)
+	var events, errs, stopped = watcher.Events(), watcher.Errors(), dispatcher.ctx.Done()
+	for events != nil || errs != nil {
+		select {
+		case <-stopped:
+			// a handler failed, or ctx is done
+			cancel()
+			stopped = nil
+
+		case event, open := <-events:
+			if !open {
+				events = nil
+				continue
+			}
+			dispatcher.Dispatch(event)
+
+		case err, open := <-errs:
+			if !open {
+				errs = nil
+				continue
+			}
+			if config.errorHandler != nil {
+				config.errorHandler(err)
+			}
+		}
+	}
+
+	if err := dispatcher.Wait(); err != nil {
+		return err
+	}
+	return watcher.Close()
+}
@@ -0,0 +1,57 @@
+package path
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchFunc(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var failed = errors.New("failed")
+
+	var mu sync.Mutex
+	var handled []string
+	var handler = func(event WatchEvent) error {
+		if filepath.Base(event.AbsolutePath) == "bad.txt" {
+			return failed
+		}
+		mu.Lock()
+		handled = append(handled, event.AbsolutePath)
+		mu.Unlock()
+		return nil
+	}
+
+	var result = make(chan error)
+	go func() {
+		result <- WatchFunc(t.Context(), dir, 0, handler, NewOpWatchFilter(fsnotify.Create))
+	}()
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchFunc to start up
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "good.txt"), []byte{}, os.ModePerm))
+	time.Sleep(time.Millisecond * 100)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "bad.txt"), []byte{}, os.ModePerm))
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, failed)
+	case <-time.After(time.Second * 5):
+		assert.Fail(t, "WatchFunc did not stop")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{filepath.Join(dir, "good.txt")}, handled)
+
+	// a missing path can not be watched
+	assert.ErrorIs(t, WatchFunc(t.Context(), filepath.Join(dir, "missing"), 0, handler), os.ErrNotExist)
+}
@@ -0,0 +1,13 @@
+//go:build linux
+
+package path
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isWatchLimit reports if err is inotify refusing a watch because fs.inotify.max_user_watches is reached.
+func isWatchLimit(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package path
+
+// isWatchLimit reports if err is the OS refusing a watch because of a limit, it is only known on linux.
+func isWatchLimit(err error) bool {
+	return false
+}
@@ -0,0 +1,110 @@
+package path
+
+import (
+	"cmp"
+	"io/fs"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Ready is set in the Op of the event WithWriteFinished publishes once a file is complete, along with every Op that
+// was held until then.
+const Ready fsnotify.Op = 1 << 31
+
+// closeWrite is fsnotify's unexported Op for inotify's IN_CLOSE_WRITE. fsnotify only reports it for watches that ask
+// for it, which can not be done from outside the package yet, so it is honoured if it ever shows up.
+const closeWrite fsnotify.Op = 1 << 7
+
+// writeTracker holds the events of files that are being written until their size and modification time have been
+// stable for a while.
+type writeTracker struct {
+	stable  time.Duration
+	pending map[string]*pendingWrite
+	seq     uint64
+}
+
+// pendingWrite is a file that is still being written.
+type pendingWrite struct {
+	event    trackedEvent
+	size     int64
+	modTime  time.Time
+	deadline time.Time // when to check the file again
+	seq      uint64    // order of the first event, so ready events keep arrival order
+}
+
+func newWriteTracker(stable time.Duration) *writeTracker {
+	return &writeTracker{stable: stable, pending: make(map[string]*pendingWrite)}
+}
+
+// add holds event for a file described by info, a create or write starts holding the file and any other event for
+// a held file is merged into it. It reports false if event is not held.
+func (wt *writeTracker) add(event trackedEvent, info fs.FileInfo, now time.Time) bool {
+	var p, has = wt.pending[event.Name]
+	if !has {
+		if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+			return false
+		}
+		wt.seq++
+		p = &pendingWrite{event: event, seq: wt.seq}
+		wt.pending[event.Name] = p
+	}
+
+	p.event.Op |= event.Op
+	p.size, p.modTime = info.Size(), info.ModTime()
+	p.deadline = now.Add(wt.stable)
+	if event.Op&closeWrite != 0 { // the writer is done, no need to wait
+		p.deadline = now
+	}
+	return true
+}
+
+// forget drops the held events of path.
+func (wt *writeTracker) forget(path string) {
+	delete(wt.pending, path)
+}
+
+// due checks every held file whose deadline has passed with stat, and returns the events of those that did not
+// change since they were last seen, in the order they were first held. Files that changed are held for another
+// stable period and files that are gone are dropped, their removal is published on its own.
+func (wt *writeTracker) due(now time.Time, stat func(string) (fs.FileInfo, error)) []trackedEvent {
+	var ready []*pendingWrite
+	for name, p := range wt.pending {
+		if now.Before(p.deadline) {
+			continue
+		}
+
+		var info, err = stat(name)
+		switch {
+		case err != nil:
+			delete(wt.pending, name)
+		case info.Size() == p.size && info.ModTime().Equal(p.modTime):
+			ready = append(ready, p)
+			delete(wt.pending, name)
+		default:
+			p.size, p.modTime = info.Size(), info.ModTime()
+			p.deadline = now.Add(wt.stable)
+		}
+	}
+
+	slices.SortFunc(ready, func(a, b *pendingWrite) int { return cmp.Compare(a.seq, b.seq) })
+
+	var events = make([]trackedEvent, len(ready))
+	for i, p := range ready {
+		events[i] = p.event
+		events[i].Op |= Ready
+	}
+	return events
+}
+
+// next returns when the earliest held file has to be checked, false if nothing is held.
+func (wt *writeTracker) next() (time.Time, bool) {
+	var earliest time.Time
+	for _, p := range wt.pending {
+		if earliest.IsZero() || p.deadline.Before(earliest) {
+			earliest = p.deadline
+		}
+	}
+	return earliest, !earliest.IsZero()
+}
@@ -0,0 +1,123 @@
+package path
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTracker(t *testing.T) {
+	t.Parallel()
+
+	var start = time.Date(2022, 06, 01, 0, 0, 0, 0, time.UTC)
+	var wt = newWriteTracker(time.Second)
+
+	var infos = make(map[string]fs.FileInfo)
+	var stat = func(name string) (fs.FileInfo, error) {
+		if info, has := infos[name]; has {
+			return info, nil
+		}
+		return nil, fs.ErrNotExist
+	}
+	var setSize = func(name string, size int64) fs.FileInfo {
+		infos[name] = fileInfo{name: name, size: size, modTime: start}
+		return infos[name]
+	}
+
+	// only creates and writes start a hold
+	assert.False(t, wt.add(newTrackedEvent("a", fsnotify.Chmod), setSize("a", 0), start))
+	assert.True(t, wt.add(newTrackedEvent("a", fsnotify.Create), setSize("a", 0), start))
+	assert.True(t, wt.add(newTrackedEvent("b", fsnotify.Create), setSize("b", 0), start))
+	assert.True(t, wt.add(newTrackedEvent("a", fsnotify.Write), setSize("a", 10), start.Add(time.Millisecond*500)))
+	assert.True(t, wt.add(newTrackedEvent("a", fsnotify.Chmod), setSize("a", 10), start.Add(time.Millisecond*500)))
+
+	next, held := wt.next()
+	assert.True(t, held)
+	assert.Equal(t, start.Add(time.Second), next)
+
+	// b grew without an event, so it is checked again later
+	setSize("b", 5)
+	assert.Empty(t, wt.due(start.Add(time.Second), stat))
+	assert.Empty(t, wt.due(start.Add(time.Millisecond*1499), stat))
+
+	var due = wt.due(start.Add(time.Millisecond*1500), stat)
+	assert.Equal(t, []trackedEvent{newTrackedEvent("a", fsnotify.Create|fsnotify.Write|fsnotify.Chmod|Ready)}, due)
+
+	due = wt.due(start.Add(time.Second*2), stat)
+	assert.Equal(t, []trackedEvent{newTrackedEvent("b", fsnotify.Create|Ready)}, due)
+
+	// the writer closing the file makes it due straight away
+	assert.True(t, wt.add(newTrackedEvent("c", fsnotify.Write|closeWrite), setSize("c", 1), start))
+	assert.Len(t, wt.due(start, stat), 1)
+
+	// files that are gone are dropped
+	assert.True(t, wt.add(newTrackedEvent("d", fsnotify.Create), setSize("d", 1), start))
+	assert.True(t, wt.add(newTrackedEvent("e", fsnotify.Create), setSize("e", 1), start))
+	wt.forget("d")
+	delete(infos, "e")
+	assert.Empty(t, wt.due(start.Add(time.Hour), stat))
+
+	_, held = wt.next()
+	assert.False(t, held)
+}
+
+func TestWatchDirWriteFinished(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var clock = newFakeClock()
+	var files = make(chan WatchEvent)
+	var errs = make(chan error)
+	var ctx, cancel = context.WithCancel(t.Context())
+
+	go func() {
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+	}()
+	go WatchDirOptions(ctx, dir, 0, false, files, errs, WithWriteFinished(time.Second), WithClock(clock))
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to start up
+
+	var file = filepath.Join(dir, "upload.bin")
+	var f, err = os.Create(file)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte("part one"))
+	assert.NoError(t, err)
+
+	time.Sleep(time.Millisecond * 250) // give time for WatchDir to process events
+
+	// nothing is published while the file may still be written
+	select {
+	case event := <-files:
+		assert.Fail(t, "event published before the file was complete", event.AbsolutePath)
+	default:
+	}
+
+	_, err = f.Write([]byte(", part two"))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	time.Sleep(time.Millisecond * 250)
+	clock.Advance(time.Second)
+
+	var event = <-files
+	assert.Equal(t, file, event.AbsolutePath)
+	assert.True(t, event.Has(Ready))
+	assert.True(t, event.Has(fsnotify.Create))
+	assert.True(t, event.Has(fsnotify.Write))
+	assert.Equal(t, int64(18), event.FileInfo.Size())
+
+	select {
+	case event := <-files:
+		assert.Fail(t, "more than one event for the file", event.AbsolutePath)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	cancel()
+}